@@ -4,3 +4,15 @@ import "embed"
 
 //go:embed controlplane-operator/**
 var ControlplaneOperator embed.FS
+
+// CRDs is a kustomization with stand-ins for the controlplane-operator CRDs,
+// applied when the CRD source is "embedded". crds/SOURCE records the operator
+// ref scripts/vendor-crds.sh replaces them from.
+//
+//go:embed crds/*.yaml
+var CRDs embed.FS
+
+// IngressNginx is the ingress-nginx controller-v1.11.3 manifest for kind.
+//
+//go:embed ingress-nginx/deploy.yaml
+var IngressNginx []byte
@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/providers"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+)
+
+func newBackupCommand() *cobra.Command {
+	var (
+		output        string
+		namespace     string
+		managementCtx string
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Archive the management plane etcd, PKI and operator config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			if output == "" {
+				output = fmt.Sprintf("kplane-backup-%s-%s.tar.gz", profile.ClusterName, time.Now().Format("20060102-150405"))
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+				return err
+			}
+			tmp := output + ".partial"
+			file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(tmp)
+			defer file.Close()
+
+			var metadata *stacklatest.BackupMetadata
+			if err := ui.Step("backup: archiving management plane", func() error {
+				var err error
+				metadata, err = stacklatest.Backup(ctx, stacklatest.BackupOptions{
+					Context:   managementCtx,
+					Namespace: namespace,
+					Metadata: stacklatest.BackupMetadata{
+						StackVersion: installedStackVersion(ctx, managementCtx, namespace),
+						IngressPort:  resolveIngressPortFromCluster(ctx, managementCtx, namespace),
+					},
+					Logf: stepLogf(cmd, ui, "backup"),
+				}, file)
+				return err
+			}); err != nil {
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+			if err := os.Rename(tmp, output); err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("ready: wrote %s (etcd snapshot %d bytes, %d member(s), %d management objects)", output, metadata.SnapshotBytes, metadata.EtcdMembers, metadata.Objects)
+			if ui.Enabled() {
+				ui.Successf("%s", msg)
+			} else {
+				fmt.Fprintln(out, msg)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Archive path (default: kplane-backup-<cluster>-<timestamp>.tar.gz)")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+func newRestoreCommand() *cobra.Command {
+	var (
+		file          string
+		provider      string
+		clusterName   string
+		crdSource     string
+		kubeconfigOut string
+		setCurrent    bool
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Recreate the management plane on a new cluster from a backup",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if provider == "" {
+				provider = profile.Provider
+			}
+			if clusterName == "" {
+				clusterName = profile.ClusterName
+			}
+			if kubeconfigOut == "" {
+				kubeconfigOut = profile.KubeconfigPath
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			archive, err := stacklatest.ReadBackup(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			defer archive.Close()
+			metadata := archive.Metadata
+
+			clusterProvider, err := providers.NewForProfile(provider, profile)
+			if err != nil {
+				return err
+			}
+			if err := clusterProvider.EnsureInstalled(); err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			exists, err := clusterProvider.ClusterExists(ctx, clusterName)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("cluster %s already exists; run kplane down first or pass --cluster-name", clusterName)
+			}
+
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			if ui.Enabled() {
+				printBanner(out)
+			}
+			fmt.Fprintf(out, "backup: taken %s from namespace %s (stack %s, %d etcd member(s))\n",
+				metadata.CreatedAt.Local().Format(time.RFC3339), metadata.Namespace, orDash(metadata.StackVersion), metadata.EtcdMembers)
+
+			resolvedStack, err := resolveStack(metadata.StackVersion)
+			if err != nil {
+				return err
+			}
+			if crdSource == "" {
+				crdSource = profile.CRDSource
+			}
+			if crdSource == "" {
+				crdSource = resolvedStack.CRDSource
+			}
+			ha := metadata.EtcdMembers > 1
+			workers := 0
+			if ha {
+				workers = stacklatest.HAMembers - 1
+			}
+			providerName := clusterProvider.Name()
+			externalHost, err := resolveExternalHost(providerName, profile.Kubeconfig.ExternalURL)
+			if err != nil {
+				return err
+			}
+			saved := metadata.EtcdStorage
+			storage, mounts, err := resolveEtcdStorage(providerName, clusterName, saved.Mode, saved.Size, saved.StorageClass, "", profile)
+			if err != nil {
+				return err
+			}
+
+			contextName, ingressPort, err := ensureManagementCluster(ctx, cmd, ui, profile, managementClusterSpec{
+				Provider:    clusterProvider,
+				Name:        clusterName,
+				Namespace:   metadata.Namespace,
+				Workers:     workers,
+				Mounts:      mounts,
+				Kubeconfig:  kubeconfigOut,
+				SetCurrent:  setCurrent,
+				IngressPort: metadata.IngressPort,
+				Mirrors:     resolveMirrors(providerName, profile.Registry.Mirror, profile),
+			})
+			if err != nil {
+				return err
+			}
+			if metadata.IngressPort != 0 && ingressPort != metadata.IngressPort {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: ingress moved from port %d to %d; fetch VCP kubeconfigs again with kplane get-credentials\n", metadata.IngressPort, ingressPort)
+			}
+
+			switch resolvedStack.Manifests {
+			case stacklatest.Name:
+				authzMode, err := stacklatest.NormalizeAuthorizationMode(profile.AuthorizationMode)
+				if err != nil {
+					return err
+				}
+				if err := ui.Step("restore: installing management plane from backup", func() error {
+					return stacklatest.Restore(ctx, stacklatest.RestoreOptions{
+						InstallOptions: stacklatest.InstallOptions{
+							Context:             contextName,
+							Namespace:           metadata.Namespace,
+							Images:              metadata.Images,
+							EtcdStorage:         storage,
+							HA:                  ha,
+							CRDSource:           crdSource,
+							InstallCRDs:         true,
+							Auth:                stacklatest.Auth{Policy: profile.Auth.Policy, IssuerTemplate: profile.Auth.IssuerTemplate},
+							AuthorizationMode:   authzMode,
+							TLSSANs:             appendSAN(append([]string{}, profile.TLSSANs...), externalHost),
+							IngressLoadBalancer: providerName == "kubeconfig",
+							Logf:                stepLogf(cmd, ui, "restore"),
+						},
+						Archive: archive,
+					})
+				}); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("stack %s uses unsupported manifests %q", resolvedStack.Version, resolvedStack.Manifests)
+			}
+			if err := ui.Step("ingress: recording port", func() error {
+				return recordManagementState(ctx, contextName, metadata.Namespace, ingressPort, profile.Kubeconfig.ExternalURL, resolvedStack.Version)
+			}); err != nil {
+				return err
+			}
+			if err := clearRestoredLocalState(ctx, contextName, metadata.Namespace); err != nil {
+				return err
+			}
+			if err := setProfileProvider(cfg, providerName); err != nil {
+				return err
+			}
+			if ui.Enabled() {
+				ui.Successf("ready: management plane restored from %s", file)
+			} else {
+				fmt.Fprintf(out, "ready: management plane restored from %s\n", file)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Backup archive written by kplane backup")
+	cmd.Flags().StringVar(&provider, "provider", "", "Cluster provider (default: kind)")
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "", "Name of the new management cluster")
+	cmd.Flags().StringVar(&crdSource, "crd-source", "", "CRD source (kustomize URL or path)")
+	cmd.Flags().StringVar(&kubeconfigOut, "kubeconfig", "", "Kubeconfig path to update")
+	cmd.Flags().BoolVar(&setCurrent, "set-current", true, "Set current kubeconfig context")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+// clearRestoredLocalState drops management state restored from the backup
+// that describes workloads the backup does not carry: the dex loopback
+// forwarder and images rolled out by dev reload.
+func clearRestoredLocalState(ctx context.Context, contextName, namespace string) error {
+	if err := recordManagementValue(ctx, contextName, namespace, oidcLoopbackKey, ""); err != nil {
+		return err
+	}
+	return clearReloadedImages(ctx, contextName, namespace)
+}
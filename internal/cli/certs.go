@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/config"
+	"github.com/kplane-dev/kplane/internal/providers"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func newCertsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Inspect and rotate management plane certificates",
+	}
+	cmd.AddCommand(newCertsCheckCommand(), newCertsRotateCommand())
+	return cmd
+}
+
+func newCertsCheckCommand() *cobra.Command {
+	var (
+		namespace     string
+		managementCtx string
+		warnWithin    time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Report certificate subjects, SANs and expiry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+
+			infos, err := stacklatest.CheckCerts(cmd.Context(), stacklatest.CertOptions{
+				Context:   managementCtx,
+				Namespace: namespace,
+			})
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "COMPONENT\tSECRET\tSUBJECT\tNOT AFTER\tEXPIRES\tSTATUS\tSANS")
+			expiring := 0
+			now := time.Now()
+			for _, info := range infos {
+				if info.Error != "" {
+					expiring++
+					fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t%s\t-\n", info.Component, info.Secret, info.Error)
+					continue
+				}
+				status := "ok"
+				remaining := info.NotAfter.Sub(now)
+				expires := "in " + duration.HumanDuration(remaining)
+				switch {
+				case remaining <= 0:
+					status = "expired"
+					expires = duration.HumanDuration(-remaining) + " ago"
+					expiring++
+				case remaining < warnWithin:
+					status = "expiring"
+					expiring++
+				}
+				sans := append(append([]string{}, info.DNSNames...), info.IPAddresses...)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", info.Component, info.Secret, info.Subject, info.NotAfter.UTC().Format(time.RFC3339), expires, status, orDash(strings.Join(sans, ",")))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if expiring > 0 {
+				fmt.Fprintf(out, "\n%d certificate(s) need attention; run `kplane certs rotate --component <name>`\n", expiring)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().DurationVar(&warnWithin, "warn-within", stacklatest.RenewWithin, "Flag certificates expiring within this duration")
+	return cmd
+}
+
+func newCertsRotateCommand() *cobra.Command {
+	var (
+		namespace     string
+		managementCtx string
+		components    []string
+		tlsSANs       []string
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Re-issue certificates from the stored CA and restart affected deployments",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			if len(components) == 0 {
+				components = []string{stacklatest.CertComponentApiserver, stacklatest.CertComponentKubeletClient, stacklatest.CertComponentAdmin, stacklatest.CertComponentIngress}
+			}
+			for _, component := range components {
+				if component == stacklatest.CertComponentCA {
+					fmt.Fprintln(cmd.ErrOrStderr(), "warning: rotating the CA re-issues every certificate; existing VCP kubeconfigs will stop working")
+					break
+				}
+			}
+
+			ui := NewUI(cmd.OutOrStdout(), profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			return ui.Step("certs: rotating "+strings.Join(components, ", "), func() error {
+				return stacklatest.RotateCerts(cmd.Context(), stacklatest.CertOptions{
+					Context:    managementCtx,
+					Namespace:  namespace,
+					Components: components,
+					TLSSANs:    append(append([]string{}, profile.TLSSANs...), tlsSANs...),
+					Logf: func(format string, args ...any) {
+						msg := fmt.Sprintf(format, args...)
+						if ui.Enabled() {
+							ui.Infof("certs: %s", msg)
+						} else {
+							fmt.Fprintf(cmd.OutOrStdout(), "certs: %s\n", msg)
+						}
+					},
+				})
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().StringSliceVar(&components, "component", nil, "Certificates to rotate: apiserver, kubelet-client, admin, ingress or ca (default: all leaf certificates)")
+	cmd.Flags().StringSliceVar(&tlsSANs, "tls-san", nil, "Extra DNS name or IP for the apiserver and ingress certificates (repeatable)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+func resolveCertsTarget(profile config.Profile, namespace, managementCtx *string) error {
+	if *namespace == "" {
+		*namespace = profile.Namespace
+	}
+	if *managementCtx == "" {
+		clusterProvider, err := providers.NewForProfile(profile.Provider, profile)
+		if err != nil {
+			return err
+		}
+		*managementCtx = clusterProvider.ContextName(profile.ClusterName)
+	}
+	return nil
+}
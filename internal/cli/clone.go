@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newCloneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Clone resources",
+	}
+	cmd.AddCommand(newCloneClusterCommand())
+	return cmd
+}
+
+func newCloneClusterCommand() *cobra.Command {
+	var (
+		className     string
+		exclude       []string
+		parallel      int
+		timeout       time.Duration
+		namespace     string
+		managementCtx string
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cluster <src> <dst>",
+		Short: "Create a new VCP and copy another VCP's objects into it (export/import)",
+		Long: `Create a new VCP and copy another VCP's objects into it.
+
+The copy is an export/import through the apiserver, not a copy of the etcd
+keyspace: every listable object in <src> is read, stripped of server-managed
+fields and applied to <dst>, so admission and defaulting run again and UIDs,
+resource versions and status are not carried over. Like kplane export, it
+skips system objects and objects owned by another object, which their
+controllers recreate in <dst>. URLs of the form /clusters/<src>/ inside
+objects are rewritten to /clusters/<dst>/.
+
+When <src> uses a class derived for it by create cluster (per-VCP auth
+settings), <dst> gets its own copy of that class.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, dst := args[0], args[1]
+			if src == dst {
+				return fmt.Errorf("source and destination are both %s", src)
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			logf := stepLogf(cmd, ui, "clone")
+			source, err := kubectl.Get(ctx, managementCtx, "controlplane", src, "")
+			if err != nil {
+				return err
+			}
+			inheritClass := className == ""
+			if inheritClass {
+				className, _, _ = unstructured.NestedString(source.Object, "spec", "classRef", "name")
+			}
+			if className == "" {
+				className = "starter"
+			}
+			if _, err := kubectl.Get(ctx, managementCtx, "controlplane", dst, ""); err == nil {
+				return fmt.Errorf("controlplane %s already exists", dst)
+			} else if !apierrors.IsNotFound(err) {
+				return err
+			}
+			var derived *unstructured.Unstructured
+			if inheritClass {
+				class, err := kubectl.Get(ctx, managementCtx, "controlplaneclass", className, "")
+				if err != nil {
+					return err
+				}
+				derived = cloneDerivedClass(class, src, dst)
+			}
+
+			srcClient, err := controlPlaneClient(ctx, managementCtx, namespace, src)
+			if err != nil {
+				return err
+			}
+			var objects []*unstructured.Unstructured
+			if err := ui.Step("clone: reading objects from "+src, func() error {
+				resources, err := srcClient.ExportObjects(ctx, exclude)
+				if err != nil {
+					return err
+				}
+				for _, resource := range resources {
+					for _, obj := range resource.Objects {
+						rewriteClusterIdentity(obj.Object, src, dst)
+						objects = append(objects, obj)
+					}
+				}
+				logf("%d objects from %d resource types", len(objects), len(resources))
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			// The derived class is only created once the export has succeeded,
+			// so a failed read leaves nothing behind.
+			if derived != nil {
+				if err := ui.Step("controlplaneclass: deriving "+derived.GetName(), func() error {
+					c, err := kubectl.ForContext(managementCtx)
+					if err != nil {
+						return err
+					}
+					return c.ApplyObject(ctx, derived)
+				}); err != nil {
+					return err
+				}
+				className = derived.GetName()
+			}
+			if err := ui.Step(fmt.Sprintf("controlplane: creating %s from class %s", dst, className), func() error {
+				_, err := ensureControlPlane(ctx, managementCtx, namespace, dst, className, timeout, logf)
+				return err
+			}); err != nil {
+				return err
+			}
+			dstClient, err := controlPlaneClient(ctx, managementCtx, namespace, dst)
+			if err != nil {
+				return err
+			}
+			start := time.Now()
+			if err := ui.Step(fmt.Sprintf("clone: copying %d objects to %s", len(objects), dst), func() error {
+				if failed := dstClient.ApplyAll(ctx, objects, parallel, applyProgress(logf, len(objects))); failed > 0 {
+					return fmt.Errorf("%d of %d objects failed to copy", failed, len(objects))
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("cloned %s to %s (%d objects in %s)", src, dst, len(objects), time.Since(start).Round(time.Millisecond))
+			if ui.Enabled() {
+				ui.Successf("%s", msg)
+				ui.Successf("  kplane get-credentials %s", dst)
+			} else {
+				fmt.Fprintln(out, msg)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&className, "class", "", "ControlPlaneClass for the new VCP (default: the source's class)")
+	cmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Resource or kind to leave out, e.g. secrets or Deployment (repeatable)")
+	cmd.Flags().IntVar(&parallel, "parallel", 32, "Objects copied concurrently")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Wait timeout for the new VCP to become ready")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+// cloneDerivedClass returns a copy of class for dst when class was derived for
+// src alone, or nil when class is shared and dst can use it as is. The copy is
+// labeled for dst so delete cluster removes it with the VCP.
+func cloneDerivedClass(class *unstructured.Unstructured, src, dst string) *unstructured.Unstructured {
+	if class.GetLabels()[derivedClassLabel] != src {
+		return nil
+	}
+	base := class.GetAnnotations()[baseClassAnnotation]
+	if base == "" {
+		base = strings.TrimPrefix(class.GetName(), src+"-")
+	}
+	spec, _, _ := unstructured.NestedMap(class.Object, "spec")
+	derived := &unstructured.Unstructured{Object: map[string]any{"spec": rewriteClusterIdentity(spec, src, dst)}}
+	derived.SetAPIVersion(class.GetAPIVersion())
+	derived.SetKind(class.GetKind())
+	derived.SetName(dst + "-" + base)
+	derived.SetLabels(map[string]string{derivedClassLabel: dst})
+	derived.SetAnnotations(map[string]string{baseClassAnnotation: base})
+	return derived
+}
+
+// rewriteClusterIdentity points URLs that address the source VCP through the
+// shared apiserver (/clusters/<src>/...) at the destination instead.
+func rewriteClusterIdentity(value any, src, dst string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = rewriteClusterIdentity(item, src, dst)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = rewriteClusterIdentity(item, src, dst)
+		}
+	case string:
+		return strings.ReplaceAll(v, "/clusters/"+src+"/", "/clusters/"+dst+"/")
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCloneDerivedClass(t *testing.T) {
+	class := func(name string, labels, annotations map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]any{
+			"spec": map[string]any{
+				"auth": map[string]any{
+					"policy":         "ManagedIssuer",
+					"issuerTemplate": "https://kplane.lan/clusters/src/control-plane",
+				},
+			},
+		}}
+		obj.SetAPIVersion("controlplane.kplane.dev/v1alpha1")
+		obj.SetKind("ControlPlaneClass")
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	tests := []struct {
+		name     string
+		class    *unstructured.Unstructured
+		wantName string
+		wantBase string
+	}{
+		{name: "shared", class: class("starter", nil, nil)},
+		{name: "derived for another VCP", class: class("other-starter", map[string]string{derivedClassLabel: "other"}, nil)},
+		{
+			name:     "derived",
+			class:    class("src-starter", map[string]string{derivedClassLabel: "src"}, map[string]string{baseClassAnnotation: "starter"}),
+			wantName: "dst-starter",
+			wantBase: "starter",
+		},
+		{
+			name:     "derived without annotation",
+			class:    class("src-ha", map[string]string{derivedClassLabel: "src"}, nil),
+			wantName: "dst-ha",
+			wantBase: "ha",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cloneDerivedClass(tt.class, "src", "dst")
+			if tt.wantName == "" {
+				if got != nil {
+					t.Fatalf("cloneDerivedClass() = %s, want nil", got.GetName())
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("cloneDerivedClass() = nil")
+			}
+			if got.GetName() != tt.wantName || got.GetKind() != "ControlPlaneClass" {
+				t.Errorf("got %s %s, want ControlPlaneClass %s", got.GetKind(), got.GetName(), tt.wantName)
+			}
+			if got.GetLabels()[derivedClassLabel] != "dst" || got.GetAnnotations()[baseClassAnnotation] != tt.wantBase {
+				t.Errorf("labels %v annotations %v", got.GetLabels(), got.GetAnnotations())
+			}
+			issuer, _, _ := unstructured.NestedString(got.Object, "spec", "auth", "issuerTemplate")
+			if issuer != "https://kplane.lan/clusters/dst/control-plane" {
+				t.Errorf("issuerTemplate = %q", issuer)
+			}
+			if policy, _, _ := unstructured.NestedString(got.Object, "spec", "auth", "policy"); policy != "ManagedIssuer" {
+				t.Errorf("policy = %q", policy)
+			}
+		})
+	}
+}
+
+func TestRewriteClusterIdentity(t *testing.T) {
+	obj := map[string]any{
+		"url":   "https://kplane.lan/clusters/src/control-plane/api",
+		"list":  []any{"/clusters/src/x", "/clusters/srcx/y", 3},
+		"other": "src",
+	}
+	rewriteClusterIdentity(obj, "src", "dst")
+	if obj["url"] != "https://kplane.lan/clusters/dst/control-plane/api" {
+		t.Errorf("url = %v", obj["url"])
+	}
+	list := obj["list"].([]any)
+	if list[0] != "/clusters/dst/x" || list[1] != "/clusters/srcx/y" || list[2] != 3 {
+		t.Errorf("list = %v", list)
+	}
+	if obj["other"] != "src" {
+		t.Errorf("other = %v", obj["other"])
+	}
+}
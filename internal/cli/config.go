@@ -37,12 +37,12 @@ func newUseContextCommand() *cobra.Command {
 				kubeconfigPath = profile.KubeconfigPath
 			}
 
-			clusterProvider, err := providers.New(profile.Provider)
+			clusterProvider, err := providers.NewForProfile(profile.Provider, profile)
 			if err != nil {
 				return err
 			}
 			name := normalizeContextName(args[0], clusterProvider.ContextPrefix())
-			if strings.HasPrefix(name, clusterProvider.ContextPrefix()) {
+			if clusterProvider.ContextPrefix() != "" && strings.HasPrefix(name, clusterProvider.ContextPrefix()) {
 				if err := clusterProvider.EnsureInstalled(); err != nil {
 					return err
 				}
@@ -56,9 +56,6 @@ func newUseContextCommand() *cobra.Command {
 				}
 			}
 			if strings.HasPrefix(name, "kplane-") {
-				if err := kubectl.EnsureInstalled(); err != nil {
-					return err
-				}
 				managementCtx := clusterProvider.ContextName(profile.ClusterName)
 				controlPlaneName := strings.TrimPrefix(name, "kplane-")
 				if controlPlaneName == "" {
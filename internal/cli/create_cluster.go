@@ -3,13 +3,19 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kplane-dev/kplane/internal/kubeconfig"
 	"github.com/kplane-dev/kplane/internal/kubectl"
 	"github.com/kplane-dev/kplane/internal/providers"
+	"github.com/kplane-dev/kplane/internal/stack"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func newCreateCommand() *cobra.Command {
@@ -42,6 +48,10 @@ func newCreateClusterCommandWithUse(useLine, short string) *cobra.Command {
 		managementCtx  string
 		quiet          bool
 		noColor        bool
+		authPolicy     string
+		issuerTemplate string
+		oidc           stacklatest.OIDC
+		oidcCAFile     string
 	)
 
 	cmd := &cobra.Command{
@@ -56,7 +66,7 @@ func newCreateClusterCommandWithUse(useLine, short string) *cobra.Command {
 			}
 			showNext := profile.UI.CreateHintCount < 3
 			ui := NewUI(cmd.OutOrStdout(), profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
-			clusterProvider, err := providers.New(profile.Provider)
+			clusterProvider, err := providers.NewForProfile(profile.Provider, profile)
 			if err != nil {
 				return err
 			}
@@ -80,8 +90,43 @@ func newCreateClusterCommandWithUse(useLine, short string) *cobra.Command {
 				managementCtx = clusterProvider.ContextName(profile.ClusterName)
 			}
 
-			if err := kubectl.EnsureInstalled(); err != nil {
-				return err
+			auth := stacklatest.Auth{Policy: authPolicy, IssuerTemplate: issuerTemplate}
+			if oidc.IssuerURL != "" {
+				if oidcCAFile != "" {
+					oidc.CAData, err = os.ReadFile(oidcCAFile)
+					if err != nil {
+						return fmt.Errorf("read oidc CA file: %w", err)
+					}
+				} else if isLoopbackURL(oidc.IssuerURL) {
+					oidc.CAData, err = kubectl.GetSecretData(cmd.Context(), managementCtx, "kplane-cluster-signing-keys", namespace, "ca.crt")
+					if err != nil {
+						return fmt.Errorf("read management plane CA: %w", err)
+					}
+				}
+				auth.OIDC = &oidc
+			} else if oidc.ClientID != "" {
+				return fmt.Errorf("--oidc-client-id requires --oidc-issuer-url")
+			}
+			if installed, ok := installedStack(cmd.Context(), managementCtx, namespace); ok {
+				if auth.OIDC != nil {
+					if err := requireStackFeature(installed, stack.FeatureOIDC, "--oidc-issuer-url"); err != nil {
+						return err
+					}
+				}
+				if authPolicy != "" && auth.ManagedIssuer() {
+					if err := requireStackFeature(installed, stack.FeatureManagedIssuer, "--auth-policy=managed"); err != nil {
+						return err
+					}
+				}
+			}
+			if authPolicy != "" || issuerTemplate != "" || auth.OIDC != nil {
+				if err := ui.Step("controlplaneclass: deriving "+name+"-"+className, func() error {
+					var err error
+					className, err = deriveControlPlaneClass(cmd.Context(), managementCtx, className, name, auth)
+					return err
+				}); err != nil {
+					return err
+				}
 			}
 
 			internalEndpoint := defaultInternalEndpoint(namespace, name)
@@ -115,6 +160,15 @@ func newCreateClusterCommandWithUse(useLine, short string) *cobra.Command {
 			}); err != nil {
 				return err
 			}
+			if err := ui.Step("controlplane: bootstrapping admin RBAC", func() error {
+				vcp, err := controlPlaneClient(cmd.Context(), managementCtx, namespace, name)
+				if err != nil {
+					return err
+				}
+				return stacklatest.BootstrapVirtualAdmin(cmd.Context(), vcp)
+			}); err != nil {
+				return err
+			}
 			secretName, secretNamespace, err := controlPlaneKubeconfigRef(cmd.Context(), managementCtx, name, namespace)
 			if err != nil {
 				return err
@@ -122,15 +176,7 @@ func newCreateClusterCommandWithUse(useLine, short string) *cobra.Command {
 			var kubeconfigData []byte
 			if err := ui.Step("kubeconfig: updating", func() error {
 				var err error
-				kubeconfigData, err = kubectl.GetSecretData(cmd.Context(), managementCtx, secretName, secretNamespace, "kubeconfig")
-				if err != nil {
-					return err
-				}
-				kubeconfigData, err = kubeconfig.RewriteServer(kubeconfigData, externalEndpoint)
-				if err != nil {
-					return err
-				}
-				kubeconfigData, err = kubeconfig.RenameContext(kubeconfigData, fmt.Sprintf("kplane-%s", name))
+				kubeconfigData, err = controlPlaneKubeconfig(cmd.Context(), managementCtx, namespace, secretName, secretNamespace, externalEndpoint, fmt.Sprintf("kplane-%s", name))
 				if err != nil {
 					return err
 				}
@@ -168,11 +214,93 @@ func newCreateClusterCommandWithUse(useLine, short string) *cobra.Command {
 	cmd.Flags().StringVar(&kubeconfigOut, "kubeconfig", "", "Kubeconfig path to update")
 	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Wait timeout for controlplane readiness")
 	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().StringVar(&authPolicy, "auth-policy", "", "Auth policy for this cluster: managed or basic (derives a per-cluster class)")
+	cmd.Flags().StringVar(&issuerTemplate, "issuer-template", "", "Issuer URL template for the managed auth policy (derives a per-cluster class)")
+	cmd.Flags().StringVar(&oidc.IssuerURL, "oidc-issuer-url", "", "OIDC issuer URL for this cluster (derives a per-cluster class)")
+	cmd.Flags().StringVar(&oidc.ClientID, "oidc-client-id", "", "OIDC client ID")
+	cmd.Flags().StringVar(&oidc.UsernameClaim, "oidc-username-claim", "", "JWT claim to use as the user name (apiserver default: sub)")
+	cmd.Flags().StringVar(&oidc.UsernamePrefix, "oidc-username-prefix", "", "Prefix added to OIDC user names")
+	cmd.Flags().StringVar(&oidc.GroupsClaim, "oidc-groups-claim", "", "JWT claim to use as the user's groups")
+	cmd.Flags().StringVar(&oidc.GroupsPrefix, "oidc-groups-prefix", "", "Prefix added to OIDC group names")
+	cmd.Flags().StringVar(&oidcCAFile, "oidc-ca-file", "", "CA bundle for the issuer (default: management plane CA for loopback issuers)")
 	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
 	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
 	return cmd
 }
 
+// deriveControlPlaneClass copies the base class into <name>-<class> with the
+// auth block overridden, so one cluster can differ without touching the
+// shared class.
+func deriveControlPlaneClass(ctx context.Context, managementCtx, baseClass, name string, auth stacklatest.Auth) (string, error) {
+	base, err := kubectl.Get(ctx, managementCtx, "controlplaneclass", baseClass, "")
+	if err != nil {
+		return "", err
+	}
+	spec, _, err := unstructured.NestedMap(base.Object, "spec")
+	if err != nil {
+		return "", fmt.Errorf("read controlplaneclass %s: %w", baseClass, err)
+	}
+	if spec == nil {
+		spec = map[string]any{}
+	}
+	baseAuth, _ := spec["auth"].(map[string]any)
+	if auth.Policy == "" && baseAuth["model"] == "basic" {
+		auth.Policy = stacklatest.AuthPolicyBasic
+	}
+	if auth.IssuerTemplate == "" {
+		auth.IssuerTemplate, _ = baseAuth["issuerTemplate"].(string)
+	}
+	authSpec, err := stacklatest.AuthSpec(auth)
+	if err != nil {
+		return "", err
+	}
+	spec["auth"] = authSpec
+
+	derivedName := name + "-" + baseClass
+	derived := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	derived.SetAPIVersion(base.GetAPIVersion())
+	derived.SetKind(base.GetKind())
+	derived.SetName(derivedName)
+	derived.SetLabels(map[string]string{derivedClassLabel: name})
+	derived.SetAnnotations(map[string]string{baseClassAnnotation: baseClass})
+
+	c, err := kubectl.ForContext(managementCtx)
+	if err != nil {
+		return "", err
+	}
+	if err := c.ApplyObject(ctx, derived); err != nil {
+		return "", err
+	}
+	return derivedName, nil
+}
+
+// ensureControlPlane creates a VCP from a class when it does not exist yet,
+// waits for it to become ready and bootstraps admin RBAC. It reports whether
+// the VCP was created.
+func ensureControlPlane(ctx context.Context, managementCtx, namespace, name, className string, timeout time.Duration, logf func(string, ...any)) (bool, error) {
+	if _, err := kubectl.Get(ctx, managementCtx, "controlplane", name, ""); err == nil {
+		return false, nil
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	externalEndpoint, err := resolveExternalEndpoint(ctx, managementCtx, namespace, name, "")
+	if err != nil {
+		return false, err
+	}
+	manifest := renderControlPlaneManifest(name, className, defaultInternalEndpoint(namespace, name), externalEndpoint)
+	if err := kubectl.Apply(ctx, kubectl.ApplyOptions{Context: managementCtx, Stdin: []byte(manifest)}); err != nil {
+		return false, err
+	}
+	if err := waitForControlPlaneReady(ctx, managementCtx, name, timeout, logf); err != nil {
+		return true, err
+	}
+	vcp, err := controlPlaneClient(ctx, managementCtx, namespace, name)
+	if err != nil {
+		return true, err
+	}
+	return true, stacklatest.BootstrapVirtualAdmin(ctx, vcp)
+}
+
 func renderControlPlaneManifest(name, className, internalEndpoint, externalEndpoint string) string {
 	return fmt.Sprintf(`apiVersion: controlplane.kplane.dev/v1alpha1
 kind: ControlPlaneEndpoint
@@ -201,21 +329,56 @@ func defaultInternalEndpoint(namespace, controlPlaneName string) string {
 	return fmt.Sprintf("https://kplane-apiserver.%s.svc.cluster.local:6443/clusters/%s/control-plane", namespace, controlPlaneName)
 }
 
-func defaultExternalEndpoint(_ context.Context, _ string, ingressPort int, controlPlaneName string) (string, error) {
-	return fmt.Sprintf("https://127.0.0.1:%d/clusters/%s/control-plane", ingressPort, controlPlaneName), nil
+// controlPlaneKubeconfig reads a VCP kubeconfig secret and rewrites it for
+// use from the host: external endpoint, management plane CA and context name.
+func controlPlaneKubeconfig(ctx context.Context, managementCtx, namespace, secretName, secretNamespace, server, contextName string) ([]byte, error) {
+	kubeconfigData, err := kubectl.GetSecretData(ctx, managementCtx, secretName, secretNamespace, "kubeconfig")
+	if err != nil {
+		return nil, err
+	}
+	kubeconfigData, err = kubeconfig.RewriteServer(kubeconfigData, server)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := kubectl.GetSecretData(ctx, managementCtx, "kplane-cluster-signing-keys", namespace, "ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("read management plane CA: %w", err)
+	}
+	kubeconfigData, err = kubeconfig.SetCertificateAuthority(kubeconfigData, caPEM)
+	if err != nil {
+		return nil, err
+	}
+	return kubeconfig.RenameContext(kubeconfigData, contextName)
+}
+
+func defaultExternalEndpoint(ctx context.Context, managementCtx, namespace, controlPlaneName string) (string, error) {
+	return fmt.Sprintf("%s/clusters/%s/control-plane", externalBaseURL(ctx, managementCtx, namespace), controlPlaneName), nil
+}
+
+// externalBaseURL is the recorded external URL of a shared cluster's ingress,
+// or the local ingress port on 127.0.0.1.
+func externalBaseURL(ctx context.Context, managementCtx, namespace string) string {
+	if namespace == "" {
+		namespace = "kplane-system"
+	}
+	if url, err := kubectl.GetJSONPath(ctx, managementCtx, "configmap", ingressConfigName, namespace, "{.data.externalURL}"); err == nil && url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return fmt.Sprintf("https://127.0.0.1:%d", resolveIngressPortFromCluster(ctx, managementCtx, namespace))
 }
 
 const (
-	defaultIngressPort = 8443
-	ingressConfigName  = "kplane-management"
+	derivedClassLabel   = "kplane.dev/controlplane"
+	baseClassAnnotation = "kplane.dev/base-class"
+	defaultIngressPort  = 8443
+	ingressConfigName   = "kplane-management"
 )
 
 func resolveExternalEndpoint(ctx context.Context, managementCtx, namespace, controlPlaneName, provided string) (string, error) {
 	if provided != "" {
 		return provided, nil
 	}
-	ingressPort := resolveIngressPortFromCluster(ctx, managementCtx, namespace)
-	return defaultExternalEndpoint(ctx, managementCtx, ingressPort, controlPlaneName)
+	return defaultExternalEndpoint(ctx, managementCtx, namespace, controlPlaneName)
 }
 
 func resolveIngressPortFromCluster(ctx context.Context, managementCtx, namespace string) int {
@@ -234,14 +397,12 @@ func resolveIngressPortFromCluster(ctx context.Context, managementCtx, namespace
 }
 
 func controlPlaneKubeconfigRef(ctx context.Context, managementCtx, controlPlaneName, fallbackNamespace string) (string, string, error) {
-	name, err := kubectl.GetJSONPath(ctx, managementCtx, "controlplane", controlPlaneName, "", "{.status.kubeconfigSecretRef.name}")
-	if err != nil {
-		return "", "", err
-	}
-	namespace, err := kubectl.GetJSONPath(ctx, managementCtx, "controlplane", controlPlaneName, "", "{.status.kubeconfigSecretRef.namespace}")
+	obj, err := kubectl.Get(ctx, managementCtx, "controlplane", controlPlaneName, "")
 	if err != nil {
 		return "", "", err
 	}
+	name, _, _ := unstructured.NestedString(obj.Object, "status", "kubeconfigSecretRef", "name")
+	namespace, _, _ := unstructured.NestedString(obj.Object, "status", "kubeconfigSecretRef", "namespace")
 	if name != "" && namespace != "" {
 		return name, namespace, nil
 	}
@@ -262,41 +423,30 @@ func waitForControlPlaneReady(ctx context.Context, managementCtx, controlPlaneNa
 	for {
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("timed out waiting for controlplane to be ready")
-		case <-ticker.C:
-			ready, err := kubectl.GetJSONPath(ctx, managementCtx, "controlplane", controlPlaneName, "", "{.status.conditions[?(@.type==\"Ready\")].status}")
-			if err != nil {
-				logWaitStatus(ctx, managementCtx, controlPlaneName, &lastLog, logf)
-				continue
+			msg := "timed out waiting for controlplane to be ready"
+			if diagnostics := controlPlaneDiagnostics(ctx, managementCtx, controlPlaneName); diagnostics != "" {
+				msg += "\n" + diagnostics
 			}
-			if ready == "True" {
+			return fmt.Errorf("%s\nrun `kplane describe cluster %s` for details", msg, controlPlaneName)
+		case <-ticker.C:
+			obj, err := kubectl.Get(ctx, managementCtx, "controlplane", controlPlaneName, "")
+			if err == nil && conditionStatus(obj, "Ready") == "True" {
 				return nil
 			}
-			logWaitStatus(ctx, managementCtx, controlPlaneName, &lastLog, logf)
+			logWaitStatus(ctx, managementCtx, obj, &lastLog, logf)
 		}
 	}
 }
 
-func logWaitStatus(ctx context.Context, managementCtx, controlPlaneName string, lastLog *time.Time, logf func(string, ...any)) {
+func logWaitStatus(ctx context.Context, managementCtx string, controlPlane *unstructured.Unstructured, lastLog *time.Time, logf func(string, ...any)) {
 	if lastLog != nil && time.Since(*lastLog) < 10*time.Second {
 		return
 	}
-	endpoint, _ := kubectl.GetJSONPath(ctx, managementCtx, "controlplane", controlPlaneName, "", "{.status.endpoint}")
-	if endpoint == "" {
-		endpointRef, _ := kubectl.GetJSONPath(ctx, managementCtx, "controlplane", controlPlaneName, "", "{.spec.endpointRef.name}")
-		if endpointRef != "" {
-			externalEndpoint, _ := kubectl.GetJSONPath(ctx, managementCtx, "controlplaneendpoint", endpointRef, "", "{.spec.externalEndpoint}")
-			if externalEndpoint != "" {
-				endpoint = externalEndpoint
-			} else {
-				internalEndpoint, _ := kubectl.GetJSONPath(ctx, managementCtx, "controlplaneendpoint", endpointRef, "", "{.spec.endpoint}")
-				if internalEndpoint != "" {
-					endpoint = internalEndpoint
-				}
-			}
-		}
+	var endpoint, ready string
+	if controlPlane != nil {
+		endpoint = controlPlaneEndpoint(ctx, managementCtx, controlPlane)
+		ready = conditionStatus(controlPlane, "Ready")
 	}
-	ready, _ := kubectl.GetJSONPath(ctx, managementCtx, "controlplane", controlPlaneName, "", "{.status.conditions[?(@.type==\"Ready\")].status}")
 	if logf != nil {
 		if endpoint == "" && ready == "" {
 			logf("controlplane status: waiting for reconcile")
@@ -314,3 +464,35 @@ func logWaitStatus(ctx context.Context, managementCtx, controlPlaneName string,
 		*lastLog = time.Now()
 	}
 }
+
+func controlPlaneEndpoint(ctx context.Context, managementCtx string, controlPlane *unstructured.Unstructured) string {
+	if endpoint, _, _ := unstructured.NestedString(controlPlane.Object, "status", "endpoint"); endpoint != "" {
+		return endpoint
+	}
+	endpointRef, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "endpointRef", "name")
+	if endpointRef == "" {
+		return ""
+	}
+	endpointObj, err := kubectl.Get(ctx, managementCtx, "controlplaneendpoint", endpointRef, "")
+	if err != nil {
+		return ""
+	}
+	if external, _, _ := unstructured.NestedString(endpointObj.Object, "spec", "externalEndpoint"); external != "" {
+		return external
+	}
+	internal, _, _ := unstructured.NestedString(endpointObj.Object, "spec", "endpoint")
+	return internal
+}
+
+func conditionStatus(obj *unstructured.Unstructured, conditionType string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range conditions {
+		cond, ok := item.(map[string]any)
+		if !ok || cond["type"] != conditionType {
+			continue
+		}
+		status, _ := cond["status"].(string)
+		return status
+	}
+	return ""
+}
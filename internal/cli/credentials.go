@@ -0,0 +1,470 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubeconfig"
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+const (
+	credentialsConfigName = "kplane-credentials"
+	credentialsNamespace  = "kplane-users"
+)
+
+var (
+	credentialKeyInvalid = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+	accountNameInvalid   = regexp.MustCompile(`[^-.a-z0-9]`)
+)
+
+type issuedCredential struct {
+	ControlPlane   string     `json:"controlPlane"`
+	User           string     `json:"user"`
+	Groups         []string   `json:"groups,omitempty"`
+	ClusterRole    string     `json:"clusterRole,omitempty"`
+	Binding        string     `json:"binding,omitempty"`
+	ServiceAccount string     `json:"serviceAccount"`
+	IssuedAt       time.Time  `json:"issuedAt"`
+	NotAfter       time.Time  `json:"notAfter"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty"`
+}
+
+func newCredentialsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "credentials",
+		Short: "Manage per-user VCP credentials",
+	}
+	cmd.AddCommand(newCredentialsListCommand(), newCredentialsRevokeCommand())
+	return cmd
+}
+
+func newCredentialsListCommand() *cobra.Command {
+	var (
+		namespace     string
+		managementCtx string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list [cluster-name]",
+		Short: "List credentials issued with get-credentials --user",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			records, err := listCredentials(cmd.Context(), managementCtx, namespace)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "CLUSTER\tUSER\tGROUPS\tCLUSTER-ROLE\tISSUED\tEXPIRES\tSTATUS")
+			for _, record := range records {
+				if len(args) == 1 && record.ControlPlane != args[0] {
+					continue
+				}
+				status := "active"
+				if record.RevokedAt != nil {
+					status = "revoked"
+				} else if time.Now().After(record.NotAfter) {
+					status = "expired"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s ago\t%s\t%s\n",
+					record.ControlPlane,
+					record.User,
+					orDash(strings.Join(record.Groups, ",")),
+					orDash(record.ClusterRole),
+					duration.HumanDuration(time.Since(record.IssuedAt)),
+					record.NotAfter.UTC().Format(time.RFC3339),
+					status,
+				)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	return cmd
+}
+
+func newCredentialsRevokeCommand() *cobra.Command {
+	var (
+		user          string
+		namespace     string
+		managementCtx string
+		kubeconfigOut string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "revoke <cluster-name>",
+		Short: "Revoke a user's access to a VCP",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if user == "" {
+				return fmt.Errorf("--user is required")
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			if kubeconfigOut == "" {
+				kubeconfigOut = profile.KubeconfigPath
+			}
+
+			ctx := cmd.Context()
+			name := args[0]
+			out := cmd.OutOrStdout()
+			record, err := getCredential(ctx, managementCtx, namespace, name, user)
+			if err != nil {
+				return err
+			}
+			account := userBindingName(user)
+			if record != nil && record.ServiceAccount != "" {
+				account = strings.TrimPrefix(record.ServiceAccount, credentialsNamespace+"/")
+			}
+			bindings := []string{account + "-impersonate"}
+			switch {
+			case record == nil:
+				bindings = append(bindings, account)
+			case record.Binding != "":
+				bindings = append(bindings, record.Binding)
+			}
+
+			vcp, err := controlPlaneClient(ctx, managementCtx, namespace, name)
+			if err != nil {
+				return err
+			}
+			deleted, err := deleteIfExists(vcp.Typed().CoreV1().ServiceAccounts(credentialsNamespace).Delete(ctx, account, metav1.DeleteOptions{}))
+			if err != nil {
+				return fmt.Errorf("delete serviceaccount %s: %w", account, err)
+			}
+			if deleted {
+				fmt.Fprintf(out, "deleted serviceaccount %s/%s in %s; its tokens are no longer accepted\n", credentialsNamespace, account, name)
+			}
+			for _, crb := range bindings {
+				deleted, err := deleteIfExists(vcp.Typed().RbacV1().ClusterRoleBindings().Delete(ctx, crb, metav1.DeleteOptions{}))
+				if err != nil {
+					return fmt.Errorf("delete clusterrolebinding %s: %w", crb, err)
+				}
+				if deleted {
+					fmt.Fprintf(out, "deleted clusterrolebinding %s in %s\n", crb, name)
+				}
+			}
+			if _, err := deleteIfExists(vcp.Typed().RbacV1().ClusterRoles().Delete(ctx, account+"-impersonate", metav1.DeleteOptions{})); err != nil {
+				return fmt.Errorf("delete clusterrole %s-impersonate: %w", account, err)
+			}
+
+			if record != nil {
+				now := time.Now().UTC()
+				record.RevokedAt = &now
+				if err := recordCredential(ctx, managementCtx, namespace, *record); err != nil {
+					return err
+				}
+			}
+
+			contextName := userContextName(user, name)
+			removed, err := kubeconfig.RemoveContext(kubeconfigOut, contextName)
+			if err != nil {
+				return err
+			}
+			if removed {
+				fmt.Fprintf(out, "removed kubeconfig context %s\n", contextName)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", "", "User whose credentials to revoke")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().StringVar(&kubeconfigOut, "kubeconfig", "", "Kubeconfig path to update")
+	return cmd
+}
+
+// controlPlaneClient returns a client for a VCP using its admin kubeconfig,
+// reached through the external endpoint.
+func controlPlaneClient(ctx context.Context, managementCtx, namespace, name string) (*kubectl.Client, error) {
+	secretName, secretNamespace, err := controlPlaneKubeconfigRef(ctx, managementCtx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	externalEndpoint, err := resolveExternalEndpoint(ctx, managementCtx, namespace, name, "")
+	if err != nil {
+		return nil, err
+	}
+	data, err := controlPlaneKubeconfig(ctx, managementCtx, namespace, secretName, secretNamespace, externalEndpoint, "kplane-"+name)
+	if err != nil {
+		return nil, err
+	}
+	return kubectl.ForKubeconfig(data)
+}
+
+func userContextName(user, controlPlane string) string {
+	return fmt.Sprintf("%s@kplane-%s", user, controlPlane)
+}
+
+// userBindingName names the service account and bindings for user. The hash
+// keeps users that sanitize to the same name, such as alice@x.com and
+// alice.x.com, apart.
+func userBindingName(user string) string {
+	name := strings.Trim(accountNameInvalid.ReplaceAllString(strings.ToLower(user), "-"), "-.")
+	if len(name) > 28 {
+		name = strings.TrimRight(name[:28], "-.")
+	}
+	sum := sha256.Sum256([]byte(user))
+	return fmt.Sprintf("kplane-user-%s-%s", name, hex.EncodeToString(sum[:])[:10])
+}
+
+// deleteIfExists reports whether a delete removed something, treating an
+// object that is already gone as success.
+func deleteIfExists(err error) (bool, error) {
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func credentialKey(controlPlane, user string) string {
+	return controlPlane + "." + credentialKeyInvalid.ReplaceAllString(user, "-")
+}
+
+func recordCredential(ctx context.Context, managementCtx, namespace string, record issuedCredential) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode credential record: %w", err)
+	}
+	key := credentialKey(record.ControlPlane, record.User)
+	patch, err := json.Marshal(map[string]any{"data": map[string]string{key: string(value)}})
+	if err != nil {
+		return fmt.Errorf("encode credential record: %w", err)
+	}
+	err = kubectl.MergePatch(ctx, managementCtx, "configmap", credentialsConfigName, namespace, patch)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+	c, err := kubectl.ForContext(managementCtx)
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: credentialsConfigName, Namespace: namespace},
+		Data:       map[string]string{key: string(value)},
+	}
+	if _, err := c.Typed().CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("create %s configmap: %w", credentialsConfigName, err)
+	}
+	return nil
+}
+
+func listCredentials(ctx context.Context, managementCtx, namespace string) ([]issuedCredential, error) {
+	obj, err := kubectl.Get(ctx, managementCtx, "configmap", credentialsConfigName, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+	records := make([]issuedCredential, 0, len(data))
+	for key, value := range data {
+		var record issuedCredential
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return nil, fmt.Errorf("decode credential record %s: %w", key, err)
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].ControlPlane != records[j].ControlPlane {
+			return records[i].ControlPlane < records[j].ControlPlane
+		}
+		return records[i].User < records[j].User
+	})
+	return records, nil
+}
+
+func getCredential(ctx context.Context, managementCtx, namespace, controlPlane, user string) (*issuedCredential, error) {
+	records, err := listCredentials(ctx, managementCtx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ControlPlane == controlPlane && records[i].User == user {
+			return &records[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// validateCredentialSubject rejects names the apiserver reserves, such as
+// system:masters, which would bypass RBAC inside the VCP.
+func validateCredentialSubject(user string, groups []string) error {
+	if strings.TrimSpace(user) == "" {
+		return fmt.Errorf("--user must not be empty")
+	}
+	if strings.HasPrefix(user, "system:") {
+		return fmt.Errorf("user %q is reserved for the apiserver", user)
+	}
+	for _, group := range groups {
+		if strings.TrimSpace(group) == "" {
+			return fmt.Errorf("--group must not be empty")
+		}
+		if strings.HasPrefix(group, "system:") {
+			return fmt.Errorf("group %q is reserved for the apiserver", group)
+		}
+	}
+	return nil
+}
+
+// issueUserCredentials gives user a service account inside the VCP that may
+// only impersonate user and groups, and returns a kubeconfig with a bound
+// token for it. The account exists in that VCP alone, so the token is
+// rejected by every other VCP, and deleting the account revokes it.
+func issueUserCredentials(ctx context.Context, managementCtx, namespace, name, user string, groups []string, clusterRole string, ttl time.Duration, externalEndpoint string) ([]byte, error) {
+	if err := validateCredentialSubject(user, groups); err != nil {
+		return nil, err
+	}
+	mode, err := stacklatest.InstalledAuthorizationMode(ctx, managementCtx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("read apiserver authorization mode: %w", err)
+	}
+	if mode == stacklatest.AuthorizationModeAlwaysAllow {
+		return nil, fmt.Errorf("the shared apiserver runs with --authorization-mode=always-allow, so per-user credentials would have full access to every VCP; run kplane up --authorization-mode rbac first")
+	}
+	vcp, err := controlPlaneClient(ctx, managementCtx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := kubectl.GetSecretData(ctx, managementCtx, "kplane-cluster-signing-keys", namespace, "ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("read management plane CA: %w", err)
+	}
+	if err := vcp.CreateNamespace(ctx, credentialsNamespace); err != nil {
+		return nil, err
+	}
+	account := userBindingName(user)
+	if err := vcp.Apply(ctx, []byte(userAccountManifest(account, user, groups))); err != nil {
+		return nil, err
+	}
+	record := issuedCredential{
+		ControlPlane:   name,
+		User:           user,
+		Groups:         groups,
+		ClusterRole:    clusterRole,
+		ServiceAccount: credentialsNamespace + "/" + account,
+		IssuedAt:       time.Now().UTC(),
+	}
+	if clusterRole != "" {
+		record.Binding = account
+		manifest := fmt.Sprintf(`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %s
+  labels:
+    kplane.dev/credential: "true"
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %s
+subjects:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: User
+    name: %q
+`, record.Binding, clusterRole, user)
+		if err := vcp.Apply(ctx, []byte(manifest)); err != nil {
+			return nil, err
+		}
+	}
+	seconds := int64(ttl.Seconds())
+	token, err := vcp.Typed().CoreV1().ServiceAccounts(credentialsNamespace).CreateToken(ctx, account, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("request token for %s: %w", account, err)
+	}
+	record.NotAfter = token.Status.ExpirationTimestamp.UTC()
+	if err := recordCredential(ctx, managementCtx, namespace, record); err != nil {
+		return nil, err
+	}
+	return kubeconfig.NewImpersonatingTokenConfig(userContextName(user, name), externalEndpoint, caPEM, token.Status.Token, user, groups)
+}
+
+func userAccountManifest(account, user string, groups []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    kplane.dev/credential: "true"
+  annotations:
+    kplane.dev/user: %[3]q
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: %[1]s-impersonate
+  labels:
+    kplane.dev/credential: "true"
+rules:
+  - apiGroups: [""]
+    resources: ["users"]
+    verbs: ["impersonate"]
+    resourceNames: [%[3]q]
+`, account, credentialsNamespace, user)
+	if len(groups) > 0 {
+		quoted := make([]string, 0, len(groups))
+		for _, group := range groups {
+			quoted = append(quoted, fmt.Sprintf("%q", group))
+		}
+		fmt.Fprintf(&b, `  - apiGroups: [""]
+    resources: ["groups"]
+    verbs: ["impersonate"]
+    resourceNames: [%s]
+`, strings.Join(quoted, ", "))
+	}
+	fmt.Fprintf(&b, `---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %[1]s-impersonate
+  labels:
+    kplane.dev/credential: "true"
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: %[1]s-impersonate
+subjects:
+  - kind: ServiceAccount
+    name: %[1]s
+    namespace: %[2]s
+`, account, credentialsNamespace)
+	return b.String()
+}
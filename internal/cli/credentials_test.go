@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateCredentialSubject(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		groups  []string
+		wantErr string
+	}{
+		{name: "user only", user: "alice"},
+		{name: "user and groups", user: "alice@example.com", groups: []string{"dev", "oncall"}},
+		{name: "empty user", user: " ", wantErr: "must not be empty"},
+		{name: "system user", user: "system:admin", wantErr: "reserved"},
+		{name: "masters group", user: "alice", groups: []string{"system:masters"}, wantErr: "reserved"},
+		{name: "other system group", user: "alice", groups: []string{"dev", "system:nodes"}, wantErr: "reserved"},
+		{name: "empty group", user: "alice", groups: []string{""}, wantErr: "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCredentialSubject(tt.user, tt.groups)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserAccountManifestGroups(t *testing.T) {
+	manifest := userAccountManifest("kplane-user-alice", "alice", nil)
+	if strings.Contains(manifest, `"groups"`) {
+		t.Error("manifest allows impersonating groups without --group")
+	}
+	manifest = userAccountManifest("kplane-user-alice", "alice", []string{"dev", "oncall"})
+	if !strings.Contains(manifest, `resourceNames: ["dev", "oncall"]`) {
+		t.Errorf("manifest does not limit group impersonation to the requested groups:\n%s", manifest)
+	}
+}
+
+func TestUserBindingName(t *testing.T) {
+	seen := map[string]string{}
+	for _, user := range []string{"alice@x.com", "alice.x.com", "Alice@x.com", "alice_x.com", strings.Repeat("a", 300)} {
+		name := userBindingName(user)
+		if other, ok := seen[name]; ok {
+			t.Errorf("%q and %q share account %s", user, other, name)
+		}
+		seen[name] = user
+		if len(name)+len("-impersonate") > 63 || !accountName.MatchString(name) {
+			t.Errorf("userBindingName(%q) = %q is not a valid object name", user, name)
+		}
+	}
+	if userBindingName("alice@x.com") != userBindingName("alice@x.com") {
+		t.Error("userBindingName is not stable")
+	}
+}
+
+var accountName = regexp.MustCompile(`^[a-z0-9]([-.a-z0-9]*[a-z0-9])?$`)
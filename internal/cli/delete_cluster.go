@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubeconfig"
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"github.com/kplane-dev/kplane/internal/providers"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDeleteCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete resources",
+	}
+	cmd.AddCommand(newDeleteClusterCommand())
+	return cmd
+}
+
+func newDeleteClusterCommand() *cobra.Command {
+	return newDeleteClusterCommandWithUse("cluster [name...]", "Delete ControlPlane resources")
+}
+
+func newDeleteClusterAliasCommand() *cobra.Command {
+	return newDeleteClusterCommandWithUse("dc [name...]", "Alias for delete cluster")
+}
+
+func newDeleteClusterCommandWithUse(useLine, short string) *cobra.Command {
+	var (
+		all            bool
+		selector       string
+		keepKubeconfig bool
+		kubeconfigOut  string
+		wait           bool
+		timeout        time.Duration
+		managementCtx  string
+		quiet          bool
+		noColor        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   useLine,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 && (all || selector != "") {
+				return fmt.Errorf("names cannot be combined with --all or --selector")
+			}
+			if len(args) == 0 && !all && selector == "" {
+				return fmt.Errorf("a name, --all or --selector is required")
+			}
+
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			ui := NewUI(cmd.OutOrStdout(), profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			clusterProvider, err := providers.NewForProfile(profile.Provider, profile)
+			if err != nil {
+				return err
+			}
+			if kubeconfigOut == "" {
+				kubeconfigOut = profile.KubeconfigPath
+			}
+			if managementCtx == "" {
+				managementCtx = clusterProvider.ContextName(profile.ClusterName)
+			}
+
+			ctx := cmd.Context()
+			names := args
+			if len(names) == 0 {
+				names, err = selectControlPlanes(ctx, managementCtx, selector)
+				if err != nil {
+					return err
+				}
+				if len(names) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "no controlplanes matched")
+					return nil
+				}
+			}
+
+			for _, name := range names {
+				if err := ui.Step("controlplane: deleting "+name, func() error {
+					return deleteControlPlane(ctx, managementCtx, name, wait, timeout)
+				}); err != nil {
+					return err
+				}
+				if !keepKubeconfig {
+					removed, err := kubeconfig.RemoveContext(kubeconfigOut, "kplane-"+name)
+					if err != nil {
+						return err
+					}
+					if removed && ui.Enabled() {
+						ui.Infof("kubeconfig: removed context kplane-%s", name)
+					}
+				}
+				if !ui.Enabled() {
+					fmt.Fprintf(cmd.OutOrStdout(), "deleted controlplane %s\n", name)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Delete all controlplanes")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector for controlplanes to delete")
+	cmd.Flags().BoolVar(&keepKubeconfig, "keep-kubeconfig", false, "Keep the kplane-<name> kubeconfig entries")
+	cmd.Flags().StringVar(&kubeconfigOut, "kubeconfig", "", "Kubeconfig path to update")
+	cmd.Flags().BoolVar(&wait, "wait", true, "Wait for finalizers to complete")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Wait timeout for controlplane deletion")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+func selectControlPlanes(ctx context.Context, managementCtx, selector string) ([]string, error) {
+	list, err := kubectl.List(ctx, managementCtx, "controlplanes", "", metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func deleteControlPlane(ctx context.Context, managementCtx, name string, wait bool, timeout time.Duration) error {
+	endpointName := name + "-endpoint"
+	controlPlane, err := kubectl.Get(ctx, managementCtx, "controlplane", name, "")
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if controlPlane != nil {
+		if ref, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "endpointRef", "name"); ref != "" {
+			endpointName = ref
+		}
+	}
+
+	if err := kubectl.Delete(ctx, managementCtx, "controlplane", name, ""); err != nil {
+		return err
+	}
+	if wait {
+		if err := kubectl.WaitForDeletion(ctx, managementCtx, "controlplane", name, "", timeout); err != nil {
+			return err
+		}
+	}
+	classes, err := kubectl.List(ctx, managementCtx, "controlplaneclasses", "", metav1.ListOptions{LabelSelector: derivedClassLabel + "=" + name})
+	if err != nil {
+		return err
+	}
+	for _, class := range classes.Items {
+		if err := kubectl.Delete(ctx, managementCtx, "controlplaneclass", class.GetName(), ""); err != nil {
+			return err
+		}
+	}
+	if err := kubectl.Delete(ctx, managementCtx, "controlplaneendpoint", endpointName, ""); err != nil {
+		return err
+	}
+	if wait {
+		return kubectl.WaitForDeletion(ctx, managementCtx, "controlplaneendpoint", endpointName, "", timeout)
+	}
+	return nil
+}
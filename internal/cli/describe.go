@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"github.com/kplane-dev/kplane/internal/providers"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+type condition struct {
+	Type               string
+	Status             string
+	Reason             string
+	Message            string
+	LastTransitionTime string
+}
+
+func newDescribeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "describe",
+		Short: "Show details of resources",
+	}
+	cmd.AddCommand(newDescribeClusterCommand())
+	return cmd
+}
+
+func newDescribeClusterCommand() *cobra.Command {
+	var managementCtx string
+
+	cmd := &cobra.Command{
+		Use:   "cluster <name>",
+		Short: "Show a ControlPlane with its endpoint, conditions and events",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if managementCtx == "" {
+				clusterProvider, err := providers.NewForProfile(profile.Provider, profile)
+				if err != nil {
+					return err
+				}
+				managementCtx = clusterProvider.ContextName(profile.ClusterName)
+			}
+			return describeControlPlane(cmd.Context(), cmd.OutOrStdout(), managementCtx, args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	return cmd
+}
+
+func describeControlPlane(ctx context.Context, out io.Writer, managementCtx, name string) error {
+	controlPlane, err := kubectl.Get(ctx, managementCtx, "controlplane", name, "")
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", controlPlane.GetName())
+	className, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "classRef", "name")
+	fmt.Fprintf(w, "Class:\t%s\n", orDash(className))
+	if created := controlPlane.GetCreationTimestamp(); !created.IsZero() {
+		fmt.Fprintf(w, "Created:\t%s (%s ago)\n", created.UTC().Format(time.RFC3339), duration.HumanDuration(time.Since(created.Time)))
+	}
+	if deleted := controlPlane.GetDeletionTimestamp(); deleted != nil {
+		fmt.Fprintf(w, "Deleting:\tsince %s (finalizers: %s)\n", deleted.UTC().Format(time.RFC3339), strings.Join(controlPlane.GetFinalizers(), ", "))
+	}
+	statusEndpoint, _, _ := unstructured.NestedString(controlPlane.Object, "status", "endpoint")
+	fmt.Fprintf(w, "Endpoint:\t%s\n", orDash(statusEndpoint))
+
+	endpointRef, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "endpointRef", "name")
+	fmt.Fprintf(w, "ControlPlaneEndpoint:\t%s\n", orDash(endpointRef))
+	var endpointObj *unstructured.Unstructured
+	if endpointRef != "" {
+		endpointObj, err = kubectl.Get(ctx, managementCtx, "controlplaneendpoint", endpointRef, "")
+		if err != nil {
+			fmt.Fprintf(w, "  Error:\t%v\n", err)
+		} else {
+			internal, _, _ := unstructured.NestedString(endpointObj.Object, "spec", "endpoint")
+			external, _, _ := unstructured.NestedString(endpointObj.Object, "spec", "externalEndpoint")
+			fmt.Fprintf(w, "  Internal:\t%s\n", orDash(internal))
+			fmt.Fprintf(w, "  External:\t%s\n", orDash(external))
+		}
+	}
+
+	secretName, _, _ := unstructured.NestedString(controlPlane.Object, "status", "kubeconfigSecretRef", "name")
+	secretNamespace, _, _ := unstructured.NestedString(controlPlane.Object, "status", "kubeconfigSecretRef", "namespace")
+	secretRef := ""
+	if secretName != "" {
+		secretRef = secretNamespace + "/" + secretName
+	}
+	fmt.Fprintf(w, "Kubeconfig Secret:\t%s\n", orDash(secretRef))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if spec, ok := controlPlane.Object["spec"]; ok {
+		data, err := yaml.Marshal(spec)
+		if err == nil {
+			fmt.Fprintln(out, "Spec:")
+			fmt.Fprintln(out, indentBlock(string(data), "  "))
+		}
+	}
+
+	fmt.Fprintln(out, "Conditions:")
+	conditions := controlPlaneConditions(controlPlane)
+	if len(conditions) == 0 {
+		fmt.Fprintln(out, "  <none>")
+	} else {
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE")
+		for _, cond := range conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", cond.Type, cond.Status, orDash(cond.Reason), orDash(cond.LastTransitionTime), cond.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out, "Events:")
+	events, err := controlPlaneEvents(ctx, managementCtx, name, endpointRef)
+	if err != nil {
+		fmt.Fprintf(out, "  <unavailable: %v>\n", err)
+		return nil
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(out, "  <none>")
+		return nil
+	}
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tREASON\tAGE\tOBJECT\tFROM\tMESSAGE")
+	for _, event := range events {
+		age := duration.HumanDuration(time.Since(kubectl.EventTime(event).Time))
+		object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", event.Type, event.Reason, age, object, eventSource(event), strings.TrimSpace(event.Message))
+	}
+	return w.Flush()
+}
+
+func controlPlaneConditions(obj *unstructured.Unstructured) []condition {
+	items, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	conditions := make([]condition, 0, len(items))
+	for _, item := range items {
+		raw, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		var cond condition
+		cond.Type, _ = raw["type"].(string)
+		cond.Status, _ = raw["status"].(string)
+		cond.Reason, _ = raw["reason"].(string)
+		cond.Message, _ = raw["message"].(string)
+		cond.LastTransitionTime, _ = raw["lastTransitionTime"].(string)
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
+
+func controlPlaneEvents(ctx context.Context, managementCtx, name, endpointRef string) ([]corev1.Event, error) {
+	events, err := kubectl.ListEvents(ctx, managementCtx, "ControlPlane", name)
+	if err != nil {
+		return nil, err
+	}
+	if endpointRef != "" {
+		endpointEvents, err := kubectl.ListEvents(ctx, managementCtx, "ControlPlaneEndpoint", endpointRef)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, endpointEvents...)
+	}
+	sortEvents(events)
+	const maxEvents = 20
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	return events, nil
+}
+
+// controlPlaneDiagnostics summarizes why a controlplane is not ready, for use
+// in timeout errors.
+func controlPlaneDiagnostics(ctx context.Context, managementCtx, name string) string {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	var lines []string
+	controlPlane, err := kubectl.Get(ctx, managementCtx, "controlplane", name, "")
+	if err != nil {
+		return fmt.Sprintf("  controlplane: %v", err)
+	}
+	conditions := controlPlaneConditions(controlPlane)
+	if len(conditions) == 0 {
+		lines = append(lines, "  conditions: none reported (is the controlplane-operator running?)")
+	}
+	for _, cond := range conditions {
+		if cond.Status == "True" {
+			continue
+		}
+		line := fmt.Sprintf("  condition %s=%s", cond.Type, cond.Status)
+		if cond.Reason != "" {
+			line += " reason=" + cond.Reason
+		}
+		if cond.Message != "" {
+			line += ": " + cond.Message
+		}
+		lines = append(lines, line)
+	}
+	endpointRef, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "endpointRef", "name")
+	if events, err := controlPlaneEvents(ctx, managementCtx, name, endpointRef); err == nil {
+		var warnings []corev1.Event
+		for _, event := range events {
+			if event.Type == corev1.EventTypeWarning {
+				warnings = append(warnings, event)
+			}
+		}
+		if len(warnings) > 3 {
+			warnings = warnings[len(warnings)-3:]
+		}
+		for _, event := range warnings {
+			lines = append(lines, fmt.Sprintf("  event %s on %s/%s: %s", event.Reason, strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name, strings.TrimSpace(event.Message)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortEvents(events []corev1.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return kubectl.EventTime(events[i]).Time.Before(kubectl.EventTime(events[j]).Time)
+	})
+}
+
+func eventSource(event corev1.Event) string {
+	if event.Source.Component != "" {
+		return event.Source.Component
+	}
+	if event.ReportingController != "" {
+		return event.ReportingController
+	}
+	return "-"
+}
+
+func indentBlock(value, prefix string) string {
+	value = strings.TrimSuffix(value, "\n")
+	lines := strings.Split(value, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,334 @@
+package cli
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"github.com/kplane-dev/kplane/internal/providers"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDevCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Inner-loop helpers for working on kplane components",
+	}
+	cmd.AddCommand(newDevReloadCommand())
+	return cmd
+}
+
+func newDevReloadCommand() *cobra.Command {
+	var (
+		from           string
+		tag            string
+		load           bool
+		provider       string
+		clusterName    string
+		rolloutTimeout time.Duration
+		readyTimeout   time.Duration
+		namespace      string
+		managementCtx  string
+		quiet          bool
+		noColor        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:       "reload <apiserver|operator>",
+		Short:     "Build or load a component image and roll it out on the management plane",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: stacklatest.ReloadComponents,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			component := args[0]
+			if !slices.Contains(stacklatest.ReloadComponents, component) {
+				return fmt.Errorf("unknown component %q (expected %s)", component, strings.Join(stacklatest.ReloadComponents, " or "))
+			}
+			if from == "" {
+				return fmt.Errorf("--from is required (source directory, image archive or image reference)")
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			if provider == "" {
+				provider = profile.Provider
+			}
+			if clusterName == "" {
+				clusterName = profile.ClusterName
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			logf := stepLogf(cmd, ui, "reload")
+
+			info, statErr := os.Stat(from)
+			image := from
+			switch {
+			case statErr == nil && info.IsDir():
+				if tag == "" {
+					tag = fmt.Sprintf("kplane-%s:dev-%s", component, time.Now().Format("20060102-150405"))
+				}
+				image = tag
+				if err := ui.Step(fmt.Sprintf("build: %s from %s", image, from), func() error {
+					return dockerBuild(cmd, from, image)
+				}); err != nil {
+					return err
+				}
+			case statErr == nil:
+				image, err = archiveImage(from, tag)
+				if err != nil {
+					return err
+				}
+			}
+
+			if load {
+				clusterProvider, err := providers.NewForProfile(provider, profile)
+				if err != nil {
+					return err
+				}
+				if err := clusterProvider.EnsureInstalled(); err != nil {
+					return err
+				}
+				if statErr == nil && !info.IsDir() {
+					err = preloadImageArchive(cmd, ui, clusterProvider, clusterName, from)
+				} else {
+					err = loadLocalImages(cmd, ui, clusterProvider, clusterName, []string{image}, false)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			images := stacklatest.Images{}
+			switch component {
+			case "apiserver":
+				images.Apiserver = image
+			case "operator":
+				images.Operator = image
+			}
+			if err := ui.Step(fmt.Sprintf("rollout: %s -> %s", component, image), func() error {
+				return stacklatest.Reload(ctx, stacklatest.ReloadOptions{
+					InstallOptions: stacklatest.InstallOptions{
+						Context:   managementCtx,
+						Namespace: namespace,
+						Images:    images,
+						Logf:      logf,
+					},
+					Component:      component,
+					RolloutTimeout: rolloutTimeout,
+				})
+			}); err != nil {
+				return err
+			}
+
+			if err := recordManagementValue(ctx, managementCtx, namespace, devImageKeys[component], image); err != nil {
+				return err
+			}
+
+			if err := ui.Step("controlplanes: probing /readyz", func() error {
+				list, err := kubectl.List(ctx, managementCtx, "controlplane", "", metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				var notReady []string
+				for _, item := range list.Items {
+					if err := waitForControlPlaneReadyz(ctx, managementCtx, namespace, item.GetName(), readyTimeout); err != nil {
+						logf("%s: %v", item.GetName(), err)
+						notReady = append(notReady, item.GetName())
+						continue
+					}
+					logf("%s ready", item.GetName())
+				}
+				if len(notReady) > 0 {
+					return fmt.Errorf("not ready after reload: %s; run kplane describe cluster <name> for details", strings.Join(notReady, ", "))
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			if ui.Enabled() {
+				ui.Successf("ready: %s running %s", component, image)
+			} else {
+				fmt.Fprintf(out, "ready: %s running %s\n", component, image)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "note: kplane up and upgrade put the stack's %s image back; pass --%s-image %s to keep this one\n", component, component, image)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Source directory to docker build, docker save archive, or local image reference")
+	cmd.Flags().StringVar(&tag, "tag", "", "Image tag to build (default: kplane-<component>:dev-<timestamp>) or to pick from a multi-image archive")
+	cmd.Flags().BoolVar(&load, "load", true, "Side-load the image into the cluster nodes (disable for images the nodes can pull)")
+	cmd.Flags().StringVar(&provider, "provider", "", "Cluster provider (default: kind)")
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "", "Management cluster name")
+	cmd.Flags().DurationVar(&rolloutTimeout, "rollout-timeout", 5*time.Minute, "How long the rollout may take")
+	cmd.Flags().DurationVar(&readyTimeout, "ready-timeout", 2*time.Minute, "How long each VCP's /readyz may take to pass after the rollout")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+// devImageKeys maps each reloadable component to the management state key that
+// records the image dev reload rolled out, so up and upgrade can say they are
+// replacing it.
+var devImageKeys = map[string]string{
+	"apiserver": "devApiserverImage",
+	"operator":  "devOperatorImage",
+}
+
+// reloadedImages returns the images dev reload rolled out that are still
+// recorded, by component.
+func reloadedImages(ctx context.Context, contextName, namespace string) map[string]string {
+	images := map[string]string{}
+	for component, key := range devImageKeys {
+		if image := managementValue(ctx, contextName, namespace, key); image != "" {
+			images[component] = image
+		}
+	}
+	return images
+}
+
+// devReloadNotes describes the reloaded images an install of images replaces.
+func devReloadNotes(reloaded map[string]string, images stacklatest.Images) []string {
+	var notes []string
+	for _, c := range []struct{ component, target string }{
+		{"apiserver", images.Apiserver},
+		{"operator", images.Operator},
+	} {
+		if image := reloaded[c.component]; image != "" && image != c.target {
+			notes = append(notes, fmt.Sprintf("%s runs %s from kplane dev reload; this replaces it with %s (pass --%s-image %s to keep it)", c.component, image, c.target, c.component, image))
+		}
+	}
+	return notes
+}
+
+// clearReloadedImages forgets the dev reload images once an install has set
+// the workloads' images again.
+func clearReloadedImages(ctx context.Context, contextName, namespace string) error {
+	for _, key := range devImageKeys {
+		if err := recordManagementValue(ctx, contextName, namespace, key, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForControlPlaneReadyz polls a VCP's /readyz through its admin
+// kubeconfig and the ingress, the path its users take, until it passes.
+func waitForControlPlaneReadyz(ctx context.Context, managementCtx, namespace, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		client, err := controlPlaneClient(ctx, managementCtx, namespace, name)
+		if err == nil {
+			_, err = client.Typed().Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+			if err == nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("/readyz did not pass within %s: %w", timeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func dockerBuild(cmd *cobra.Command, dir, image string) error {
+	var output bytes.Buffer
+	build := exec.CommandContext(cmd.Context(), "docker", "build", "-t", image, dir)
+	build.Stdout = &output
+	build.Stderr = &output
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("docker build: %w\n%s", err, lastLines(output.String(), 20))
+	}
+	return nil
+}
+
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// archiveImage returns the image a docker save archive holds. Archives with
+// several tags need want to pick one.
+func archiveImage(path, want string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var r io.Reader = reader
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("%s has no manifest.json; is it a docker save archive?", path)
+		}
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", path, err)
+		}
+		if header.Name != "manifest.json" {
+			continue
+		}
+		var manifest []struct {
+			RepoTags []string
+		}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return "", fmt.Errorf("read %s manifest: %w", path, err)
+		}
+		var tags []string
+		for _, entry := range manifest {
+			tags = append(tags, entry.RepoTags...)
+		}
+		switch {
+		case want != "":
+			if !slices.Contains(tags, want) {
+				return "", fmt.Errorf("%s does not contain %s (found %s)", path, want, strings.Join(tags, ", "))
+			}
+			return want, nil
+		case len(tags) == 1:
+			return tags[0], nil
+		case len(tags) == 0:
+			return "", fmt.Errorf("%s has no tagged images; save it with docker save <name:tag>", path)
+		default:
+			return "", fmt.Errorf("%s holds %s; pick one with --tag", path, strings.Join(tags, ", "))
+		}
+	}
+}
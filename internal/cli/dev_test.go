@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+)
+
+func TestDevReloadNotes(t *testing.T) {
+	images := stacklatest.Images{Apiserver: "apiserver:v1", Operator: "operator:v1"}
+	tests := []struct {
+		name     string
+		reloaded map[string]string
+		want     []string
+	}{
+		{name: "nothing reloaded"},
+		{name: "kept", reloaded: map[string]string{"apiserver": "apiserver:v1"}},
+		{
+			name:     "replaced",
+			reloaded: map[string]string{"apiserver": "kplane-apiserver:dev"},
+			want:     []string{"apiserver runs kplane-apiserver:dev from kplane dev reload; this replaces it with apiserver:v1 (pass --apiserver-image kplane-apiserver:dev to keep it)"},
+		},
+		{
+			name:     "both",
+			reloaded: map[string]string{"operator": "op:dev", "apiserver": "as:dev"},
+			want: []string{
+				"apiserver runs as:dev from kplane dev reload; this replaces it with apiserver:v1 (pass --apiserver-image as:dev to keep it)",
+				"operator runs op:dev from kplane dev reload; this replaces it with operator:v1 (pass --operator-image op:dev to keep it)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := devReloadNotes(tt.reloaded, images); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("devReloadNotes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	for _, component := range stacklatest.ReloadComponents {
+		if devImageKeys[component] == "" {
+			t.Errorf("no management state key for reloadable component %s", component)
+		}
+	}
+}
@@ -12,7 +12,7 @@ func newDoctorCommand() *cobra.Command {
 		Use:   "doctor",
 		Short: "Check local prerequisites for kplane",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			checks := []string{"kind", "kubectl", "docker"}
+			checks := []string{"kind", "docker"}
 			var missing []string
 			for _, bin := range checks {
 				if _, err := exec.LookPath(bin); err != nil {
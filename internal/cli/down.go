@@ -26,7 +26,7 @@ func newDownCommand() *cobra.Command {
 			if clusterName == "" {
 				clusterName = profile.ClusterName
 			}
-			clusterProvider, err := providers.New(provider)
+			clusterProvider, err := providers.NewForProfile(provider, profile)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,397 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func newExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export resources",
+	}
+	cmd.AddCommand(newExportClusterCommand())
+	return cmd
+}
+
+func newImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import resources",
+	}
+	cmd.AddCommand(newImportClusterCommand())
+	return cmd
+}
+
+func newExportClusterCommand() *cobra.Command {
+	var (
+		format        string
+		dest          string
+		exclude       []string
+		namespace     string
+		managementCtx string
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cluster <name>",
+		Short: "Write a VCP's objects as clean manifests to a directory or tarball",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if format != "dir" && format != "tar" {
+				return fmt.Errorf("unsupported output %q (use dir or tar)", format)
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			if dest == "" {
+				dest = name + "-export"
+				if format == "tar" {
+					dest += ".tar.gz"
+				}
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			vcp, err := controlPlaneClient(ctx, managementCtx, namespace, name)
+			if err != nil {
+				return err
+			}
+			var resources []kubectl.ExportedResource
+			if err := ui.Step("export: listing served resources in "+name, func() error {
+				var err error
+				resources, err = vcp.ExportObjects(ctx, exclude)
+				return err
+			}); err != nil {
+				return err
+			}
+
+			var w manifestWriter
+			if format == "tar" {
+				w, err = newTarManifestWriter(dest)
+			} else {
+				w, err = newDirManifestWriter(dest)
+			}
+			if err != nil {
+				return err
+			}
+			count := 0
+			for _, resource := range resources {
+				for _, obj := range resource.Objects {
+					data, err := yaml.Marshal(obj.Object)
+					if err != nil {
+						_ = w.Close()
+						return fmt.Errorf("encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+					}
+					if err := w.Write(exportPath(resource.Resource, obj), data); err != nil {
+						_ = w.Close()
+						return err
+					}
+					count++
+				}
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("exported %d objects from %d resource types in %s to %s", count, len(resources), name, dest)
+			if ui.Enabled() {
+				ui.Successf("%s", msg)
+			} else {
+				fmt.Fprintln(out, msg)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", "dir", "Output format: dir or tar")
+	cmd.Flags().StringVar(&dest, "path", "", "Output directory or archive (default: <name>-export[.tar.gz])")
+	cmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Resource or kind to leave out, e.g. secrets or Deployment (repeatable)")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+func newImportClusterCommand() *cobra.Command {
+	var (
+		file          string
+		className     string
+		create        bool
+		parallel      int
+		timeout       time.Duration
+		namespace     string
+		managementCtx string
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cluster <name>",
+		Short: "Apply an exported VCP into a new or existing VCP",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			objects, err := readExport(file)
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			logf := stepLogf(cmd, ui, "import")
+			if create {
+				if err := ui.Step("controlplane: ensuring "+name, func() error {
+					created, err := ensureControlPlane(ctx, managementCtx, namespace, name, className, timeout, logf)
+					if created && err == nil {
+						logf("created %s from class %s", name, className)
+					}
+					return err
+				}); err != nil {
+					return err
+				}
+			}
+			vcp, err := controlPlaneClient(ctx, managementCtx, namespace, name)
+			if err != nil {
+				return err
+			}
+
+			if err := ui.Step(fmt.Sprintf("import: applying %d objects to %s", len(objects), name), func() error {
+				if failed := vcp.ApplyAll(ctx, objects, parallel, applyProgress(logf, len(objects))); failed > 0 {
+					return fmt.Errorf("%d of %d objects failed to import", failed, len(objects))
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("imported %d objects into %s", len(objects), name)
+			if ui.Enabled() {
+				ui.Successf("%s", msg)
+			} else {
+				fmt.Fprintln(out, msg)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Directory or tar.gz written by kplane export cluster")
+	cmd.Flags().BoolVar(&create, "create", true, "Create the VCP when it does not exist")
+	cmd.Flags().StringVar(&className, "class", "starter", "ControlPlaneClass for a VCP created by import")
+	cmd.Flags().IntVar(&parallel, "parallel", 16, "Objects applied concurrently")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Wait timeout for a created VCP to become ready")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+// applyProgress logs failures as they happen and a running count roughly
+// every tenth of the objects.
+func applyProgress(logf func(string, ...any), total int) func(*unstructured.Unstructured, error) {
+	var (
+		mu   sync.Mutex
+		done int
+	)
+	step := total / 10
+	if step < 1 {
+		step = 1
+	}
+	return func(obj *unstructured.Unstructured, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if err != nil {
+			logf("%v", err)
+		}
+		if done%step == 0 || done == total {
+			logf("applied %d/%d objects", done, total)
+		}
+	}
+}
+
+// exportPath lays objects out as cluster/<resource>/<name>.yaml and
+// namespaces/<namespace>/<resource>/<name>.yaml, with the group appended to
+// the resource for non-core APIs.
+func exportPath(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) string {
+	resource := gvr.GroupResource().String()
+	if obj.GetNamespace() == "" {
+		return path.Join("cluster", resource, obj.GetName()+".yaml")
+	}
+	return path.Join("namespaces", obj.GetNamespace(), resource, obj.GetName()+".yaml")
+}
+
+type manifestWriter interface {
+	Write(name string, data []byte) error
+	Close() error
+}
+
+type dirManifestWriter struct {
+	root string
+}
+
+func newDirManifestWriter(root string) (*dirManifestWriter, error) {
+	if entries, err := os.ReadDir(root); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s already exists and is not empty", root)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirManifestWriter{root: root}, nil
+}
+
+func (w *dirManifestWriter) Write(name string, data []byte) error {
+	target := filepath.Join(w.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0o600)
+}
+
+func (w *dirManifestWriter) Close() error {
+	return nil
+}
+
+type tarManifestWriter struct {
+	file *os.File
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	now  time.Time
+}
+
+func newTarManifestWriter(target string) (*tarManifestWriter, error) {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	return &tarManifestWriter{file: file, gz: gz, tw: tar.NewWriter(gz), now: time.Now()}, nil
+}
+
+func (w *tarManifestWriter) Write(name string, data []byte) error {
+	if err := w.tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: w.now}); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if _, err := w.tw.Write(data); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+func (w *tarManifestWriter) Close() error {
+	err := w.tw.Close()
+	if gzErr := w.gz.Close(); err == nil {
+		err = gzErr
+	}
+	if fileErr := w.file.Close(); err == nil {
+		err = fileErr
+	}
+	if err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+// readExport decodes every manifest in an export directory or tar.gz.
+func readExport(source string) ([]*unstructured.Unstructured, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	add := func(name string, data []byte) error {
+		decoded, err := kubectl.DecodeManifest(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		objects = append(objects, decoded...)
+		return nil
+	}
+	if info.IsDir() {
+		err := filepath.WalkDir(source, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isManifestFile(p) {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return add(p, data)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return objects, nil
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", source, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", source, err)
+		}
+		if header.Typeflag != tar.TypeReg || !isManifestFile(header.Name) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", source, err)
+		}
+		if err := add(header.Name, data); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isManifestFile(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".json")
+}
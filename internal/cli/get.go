@@ -2,16 +2,44 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/kplane-dev/kplane/internal/kubeconfig"
 	"github.com/kplane-dev/kplane/internal/kubectl"
 	"github.com/kplane-dev/kplane/internal/providers"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/duration"
 )
 
+const (
+	clusterTypeManagement = "management"
+	clusterTypeVCP        = "vcp"
+)
+
+type clusterInfo struct {
+	Name              string     `json:"name" yaml:"name"`
+	Type              string     `json:"type" yaml:"type"`
+	Provider          string     `json:"provider" yaml:"provider"`
+	Context           string     `json:"context" yaml:"context"`
+	HasContext        bool       `json:"hasContext" yaml:"hasContext"`
+	Current           bool       `json:"current" yaml:"current"`
+	Ready             string     `json:"ready,omitempty" yaml:"ready,omitempty"`
+	Endpoint          string     `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	InternalEndpoint  string     `json:"internalEndpoint,omitempty" yaml:"internalEndpoint,omitempty"`
+	Class             string     `json:"class,omitempty" yaml:"class,omitempty"`
+	KubeconfigSecret  string     `json:"kubeconfigSecret,omitempty" yaml:"kubeconfigSecret,omitempty"`
+	CreationTimestamp *time.Time `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
+}
+
 func newGetCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get",
@@ -22,12 +50,21 @@ func newGetCommand() *cobra.Command {
 }
 
 func newGetClustersCommand() *cobra.Command {
-	var kubeconfigPath string
+	var (
+		kubeconfigPath string
+		output         string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "clusters",
 		Short: "List kplane and management contexts",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			switch output {
+			case "table", "wide", "json", "yaml", "name":
+			default:
+				return fmt.Errorf("unsupported output format %q (use table, wide, json, yaml or name)", output)
+			}
+
 			cfg := mustConfig()
 			profile, err := cfg.ActiveProfile()
 			if err != nil {
@@ -37,12 +74,16 @@ func newGetClustersCommand() *cobra.Command {
 				kubeconfigPath = profile.KubeconfigPath
 			}
 
-			_, current, err := kubeconfig.ListContexts(kubeconfigPath)
+			contexts, current, err := kubeconfig.ListContexts(kubeconfigPath)
 			if err != nil {
 				return err
 			}
+			knownContexts := map[string]bool{}
+			for _, name := range contexts {
+				knownContexts[name] = true
+			}
 
-			clusterProvider, err := providers.New(profile.Provider)
+			clusterProvider, err := providers.NewForProfile(profile.Provider, profile)
 			if err != nil {
 				return err
 			}
@@ -56,13 +97,17 @@ func newGetClustersCommand() *cobra.Command {
 			}
 			sort.Strings(clusters)
 
+			var infos []clusterInfo
 			for _, cluster := range clusters {
 				ctxName := clusterProvider.ContextName(cluster)
-				marker := " "
-				if ctxName == current {
-					marker = "*"
-				}
-				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", marker, ctxName)
+				infos = append(infos, clusterInfo{
+					Name:       cluster,
+					Type:       clusterTypeManagement,
+					Provider:   clusterProvider.Name(),
+					Context:    ctxName,
+					HasContext: knownContexts[ctxName],
+					Current:    ctxName == current,
+				})
 			}
 
 			managementCtx := clusterProvider.ContextName(profile.ClusterName)
@@ -70,20 +115,18 @@ func newGetClustersCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			sort.Strings(controlplanes)
-			for _, name := range controlplanes {
-				ctxName := "kplane-" + name
-				marker := " "
-				if ctxName == current {
-					marker = "*"
-				}
-				fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", marker, ctxName)
+			for _, info := range controlplanes {
+				info.Provider = clusterProvider.Name()
+				info.HasContext = knownContexts[info.Context]
+				info.Current = info.Context == current
+				infos = append(infos, info)
 			}
-			return nil
+			return printClusters(cmd.OutOrStdout(), output, infos)
 		},
 	}
 
 	cmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Kubeconfig path to read")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, wide, json, yaml or name")
 	return cmd
 }
 
@@ -97,13 +140,109 @@ func isKplaneContext(name, providerPrefix, managementCluster string) bool {
 	return false
 }
 
-func listControlPlanes(ctx context.Context, managementCtx string) ([]string, error) {
-	out, err := kubectl.GetJSONPath(ctx, managementCtx, "controlplanes", "", "", "{.items[*].metadata.name}")
+func listControlPlanes(ctx context.Context, managementCtx string) ([]clusterInfo, error) {
+	controlPlanes, err := kubectl.List(ctx, managementCtx, "controlplanes", "", metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
-	if strings.TrimSpace(out) == "" {
-		return nil, nil
+	endpoints := map[string]*unstructured.Unstructured{}
+	if list, err := kubectl.List(ctx, managementCtx, "controlplaneendpoints", "", metav1.ListOptions{}); err == nil {
+		for i := range list.Items {
+			endpoints[list.Items[i].GetName()] = &list.Items[i]
+		}
+	}
+
+	infos := make([]clusterInfo, 0, len(controlPlanes.Items))
+	for i := range controlPlanes.Items {
+		obj := &controlPlanes.Items[i]
+		info := clusterInfo{
+			Name:    obj.GetName(),
+			Type:    clusterTypeVCP,
+			Context: "kplane-" + obj.GetName(),
+			Ready:   conditionStatus(obj, "Ready"),
+		}
+		created := obj.GetCreationTimestamp().Time
+		if !created.IsZero() {
+			info.CreationTimestamp = &created
+		}
+		info.Class, _, _ = unstructured.NestedString(obj.Object, "spec", "classRef", "name")
+		info.Endpoint, _, _ = unstructured.NestedString(obj.Object, "status", "endpoint")
+		if endpointRef, _, _ := unstructured.NestedString(obj.Object, "spec", "endpointRef", "name"); endpointRef != "" {
+			if endpoint, ok := endpoints[endpointRef]; ok {
+				if external, _, _ := unstructured.NestedString(endpoint.Object, "spec", "externalEndpoint"); external != "" {
+					info.Endpoint = external
+				}
+				info.InternalEndpoint, _, _ = unstructured.NestedString(endpoint.Object, "spec", "endpoint")
+			}
+		}
+		secretName, _, _ := unstructured.NestedString(obj.Object, "status", "kubeconfigSecretRef", "name")
+		secretNamespace, _, _ := unstructured.NestedString(obj.Object, "status", "kubeconfigSecretRef", "namespace")
+		if secretName != "" {
+			info.KubeconfigSecret = secretNamespace + "/" + secretName
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func printClusters(out io.Writer, output string, infos []clusterInfo) error {
+	if infos == nil {
+		infos = []clusterInfo{}
+	}
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(infos)
+		if err != nil {
+			return fmt.Errorf("encode yaml: %w", err)
+		}
+		fmt.Fprint(out, string(data))
+		return nil
+	case "name":
+		for _, info := range infos {
+			fmt.Fprintln(out, info.Context)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	header := "CURRENT\tNAME\tTYPE\tPROVIDER\tREADY\tENDPOINT\tCLASS\tAGE\tCONTEXT"
+	if output == "wide" {
+		header += "\tINTERNAL-ENDPOINT\tKUBECONFIG-SECRET"
+	}
+	fmt.Fprintln(w, header)
+	for _, info := range infos {
+		marker := ""
+		if info.Current {
+			marker = "*"
+		}
+		age := ""
+		if info.CreationTimestamp != nil {
+			age = duration.HumanDuration(time.Since(*info.CreationTimestamp))
+		}
+		contextName := ""
+		if info.HasContext {
+			contextName = info.Context
+		}
+		row := []string{marker, info.Name, info.Type, info.Provider, orDash(info.Ready), orDash(info.Endpoint), orDash(info.Class), orDash(age), orDash(contextName)}
+		if output == "wide" {
+			row = append(row, orDash(info.InternalEndpoint), orDash(info.KubeconfigSecret))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
 	}
-	return strings.Fields(out), nil
+	return value
 }
@@ -18,6 +18,13 @@ func newGetCredentialsCommand() *cobra.Command {
 		setCurrent    bool
 		region        string
 		project       string
+		user          string
+		groups        []string
+		clusterRole   string
+		ttl           time.Duration
+		useOIDC       bool
+		oidcSecret    string
+		oidcScopes    []string
 	)
 
 	cmd := &cobra.Command{
@@ -25,6 +32,14 @@ func newGetCredentialsCommand() *cobra.Command {
 		Short: "Update kubeconfig for a cluster",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if user == "" && (len(groups) > 0 || clusterRole != "") {
+				return fmt.Errorf("--group and --cluster-role require --user")
+			}
+			if user != "" {
+				if err := validateCredentialSubject(user, groups); err != nil {
+					return err
+				}
+			}
 			cfg := mustConfig()
 			profile, err := cfg.ActiveProfile()
 			if err != nil {
@@ -41,16 +56,13 @@ func newGetCredentialsCommand() *cobra.Command {
 				kubeconfigOut = profile.KubeconfigPath
 			}
 
-			clusterProvider, err := providers.New(provider)
+			clusterProvider, err := providers.NewForProfile(provider, profile)
 			if err != nil {
 				return err
 			}
 			if err := clusterProvider.EnsureInstalled(); err != nil {
 				return err
 			}
-			if err := kubectl.EnsureInstalled(); err != nil {
-				return err
-			}
 
 			_ = region
 			_ = project
@@ -58,6 +70,9 @@ func newGetCredentialsCommand() *cobra.Command {
 			if exists, err := clusterProvider.ClusterExists(cmd.Context(), clusterName); err != nil {
 				return err
 			} else if exists {
+				if user != "" {
+					return fmt.Errorf("--user is only supported for VCPs, not the management cluster %s", clusterName)
+				}
 				kubeconfigData, err := clusterProvider.GetKubeconfig(cmd.Context(), clusterName)
 				if err != nil {
 					return err
@@ -67,31 +82,51 @@ func newGetCredentialsCommand() *cobra.Command {
 
 			managementCtx := clusterProvider.ContextName(profile.ClusterName)
 			timeout := 5 * time.Minute
-			ready, err := kubectl.GetJSONPath(cmd.Context(), managementCtx, "controlplane", clusterName, "", "{.status.conditions[?(@.type==\"Ready\")].status}")
-			if err != nil || ready != "True" {
+			controlPlane, err := kubectl.Get(cmd.Context(), managementCtx, "controlplane", clusterName, "")
+			if err != nil || conditionStatus(controlPlane, "Ready") != "True" {
 				fmt.Fprintln(cmd.OutOrStdout(), "waiting for controlplane to reconcile...")
 				if err := waitForControlPlaneReady(cmd.Context(), managementCtx, clusterName, timeout, nil); err != nil {
 					return err
 				}
 			}
 
-			externalEndpoint, err := defaultExternalEndpoint(cmd.Context(), managementCtx, resolveIngressPortFromCluster(cmd.Context(), managementCtx, profile.Namespace), clusterName)
+			externalEndpoint, err := defaultExternalEndpoint(cmd.Context(), managementCtx, profile.Namespace, clusterName)
 			if err != nil {
 				return err
 			}
-			secretName, secretNamespace, err := controlPlaneKubeconfigRef(cmd.Context(), managementCtx, clusterName, profile.Namespace)
-			if err != nil {
-				return err
+			if user != "" {
+				kubeconfigData, err := issueUserCredentials(cmd.Context(), managementCtx, profile.Namespace, clusterName, user, groups, clusterRole, ttl, externalEndpoint)
+				if err != nil {
+					return err
+				}
+				if err := kubeconfig.MergeAndWrite(kubeconfigOut, kubeconfigData, setCurrent); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote context %s\n", userContextName(user, clusterName))
+				if clusterRole == "" {
+					fmt.Fprintln(cmd.OutOrStdout(), "note: no --cluster-role given; grant access inside the VCP with a binding for this user or group")
+				}
+				return nil
 			}
-			kubeconfigData, err := kubectl.GetSecretData(cmd.Context(), managementCtx, secretName, secretNamespace, "kubeconfig")
-			if err != nil {
-				return err
+			if useOIDC {
+				oidc, err := controlPlaneOIDC(cmd.Context(), managementCtx, clusterName)
+				if err != nil {
+					return err
+				}
+				if oidc != nil {
+					kubeconfigData, err := oidcKubeconfig(cmd.Context(), managementCtx, profile.Namespace, clusterName, externalEndpoint, oidc, oidcSecret, oidcScopes)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "wrote OIDC context kplane-%s (issuer %s); requires kubelogin (kubectl oidc-login)\n", clusterName, oidc.IssuerURL)
+					return kubeconfig.MergeAndWrite(kubeconfigOut, kubeconfigData, setCurrent)
+				}
 			}
-			kubeconfigData, err = kubeconfig.RewriteServer(kubeconfigData, externalEndpoint)
+			secretName, secretNamespace, err := controlPlaneKubeconfigRef(cmd.Context(), managementCtx, clusterName, profile.Namespace)
 			if err != nil {
 				return err
 			}
-			kubeconfigData, err = kubeconfig.RenameContext(kubeconfigData, fmt.Sprintf("kplane-%s", clusterName))
+			kubeconfigData, err := controlPlaneKubeconfig(cmd.Context(), managementCtx, profile.Namespace, secretName, secretNamespace, externalEndpoint, fmt.Sprintf("kplane-%s", clusterName))
 			if err != nil {
 				return err
 			}
@@ -105,6 +140,13 @@ func newGetCredentialsCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&setCurrent, "set-current", true, "Set current kubeconfig context")
 	cmd.Flags().StringVar(&region, "region", "", "Region (provider specific)")
 	cmd.Flags().StringVar(&project, "project", "", "Project (provider specific)")
+	cmd.Flags().StringVar(&user, "user", "", "Issue a token for this user, valid only in this VCP, instead of using the shared admin credentials")
+	cmd.Flags().StringSliceVar(&groups, "group", nil, "Group the user acts in (repeatable; system:* groups are rejected)")
+	cmd.Flags().StringVar(&clusterRole, "cluster-role", "", "ClusterRole to bind to the user inside the VCP")
+	cmd.Flags().BoolVar(&useOIDC, "oidc", true, "Write an exec-based OIDC user when the cluster's class configures OIDC")
+	cmd.Flags().StringVar(&oidcSecret, "oidc-client-secret", "", "OIDC client secret for confidential clients")
+	cmd.Flags().StringSliceVar(&oidcScopes, "oidc-extra-scope", []string{"email"}, "Extra scopes kubelogin requests, e.g. groups when the issuer only returns the groups claim for that scope (repeatable)")
+	cmd.Flags().DurationVar(&ttl, "ttl", 365*24*time.Hour, "Validity of the user token")
 
 	return cmd
 }
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kplane-dev/kplane/internal/config"
+	providerpkg "github.com/kplane-dev/kplane/internal/provider"
+	"github.com/kplane-dev/kplane/internal/providers"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+)
+
+func newImagesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "List, preload and side-load the images the management plane runs",
+	}
+	cmd.AddCommand(newImagesListCommand(), newImagesPreloadCommand(), newImagesLoadCommand())
+	return cmd
+}
+
+func newImagesListCommand() *cobra.Command {
+	var (
+		stackVersion string
+		required     bool
+		wide         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Print the stack's images, one per line",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			images, err := resolveStackImages(profile, stackVersion)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if !wide {
+				for _, image := range images {
+					if required && image.Optional {
+						continue
+					}
+					fmt.Fprintln(out, image.Image)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "COMPONENT\tIMAGE\tUSED BY")
+			for _, image := range images {
+				if required && image.Optional {
+					continue
+				}
+				usedBy := "up"
+				if image.Optional {
+					usedBy = "optional"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", image.Component, image.Image, usedBy)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&stackVersion, "stack-version", "", "Stack version (default: profile stackVersion)")
+	cmd.Flags().BoolVar(&required, "required", false, "Only list images kplane up needs")
+	cmd.Flags().BoolVar(&wide, "wide", false, "Show the component using each image")
+	return cmd
+}
+
+func newImagesPreloadCommand() *cobra.Command {
+	var (
+		archive     string
+		provider    string
+		clusterName string
+		quiet       bool
+		noColor     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "preload",
+		Short: "Load images from a docker save archive into the management cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if archive == "" {
+				return fmt.Errorf("--archive is required")
+			}
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if provider == "" {
+				provider = profile.Provider
+			}
+			if clusterName == "" {
+				clusterName = profile.ClusterName
+			}
+			clusterProvider, err := providers.NewForProfile(provider, profile)
+			if err != nil {
+				return err
+			}
+			if err := clusterProvider.EnsureInstalled(); err != nil {
+				return err
+			}
+			ui := NewUI(cmd.OutOrStdout(), profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			if err := preloadImageArchive(cmd, ui, clusterProvider, clusterName, archive); err != nil {
+				return err
+			}
+			if ui.Enabled() {
+				ui.Successf("ready: loaded %s into %s", archive, clusterName)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "loaded %s into %s\n", archive, clusterName)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&archive, "archive", "", "Tarball written by docker save (e.g. docker save $(kplane images list) -o kplane-images.tar)")
+	cmd.Flags().StringVar(&provider, "provider", "", "Cluster provider (default: kind)")
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "", "Management cluster name")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+func newImagesLoadCommand() *cobra.Command {
+	var (
+		provider    string
+		clusterName string
+		quiet       bool
+		noColor     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "load <image>...",
+		Short: "Copy images from the local Docker daemon into the management cluster",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if provider == "" {
+				provider = profile.Provider
+			}
+			if clusterName == "" {
+				clusterName = profile.ClusterName
+			}
+			clusterProvider, err := providers.NewForProfile(provider, profile)
+			if err != nil {
+				return err
+			}
+			if err := clusterProvider.EnsureInstalled(); err != nil {
+				return err
+			}
+			ui := NewUI(cmd.OutOrStdout(), profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			if err := loadLocalImages(cmd, ui, clusterProvider, clusterName, args, false); err != nil {
+				return err
+			}
+			if ui.Enabled() {
+				ui.Successf("ready: loaded %d image(s) into %s", len(args), clusterName)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "loaded %d image(s) into %s\n", len(args), clusterName)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "Cluster provider (default: kind)")
+	cmd.Flags().StringVar(&clusterName, "cluster-name", "", "Management cluster name")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+// resolveStackImages applies the profile's image overrides on top of the
+// stack's pinned images, like up does.
+func resolveStackImages(profile config.Profile, stackVersion string) ([]stacklatest.StackImage, error) {
+	if stackVersion == "" {
+		stackVersion = profile.StackVersion
+	}
+	s, err := resolveStack(stackVersion)
+	if err != nil {
+		return nil, err
+	}
+	apiserverImg, operatorImg, etcdImg, crdSource := profile.Images.Apiserver, profile.Images.Operator, profile.Images.Etcd, ""
+	applyStackDefaults(s, &apiserverImg, &operatorImg, &etcdImg, &crdSource)
+	return stacklatest.StackImages(stacklatest.Images{Apiserver: apiserverImg, Operator: operatorImg, Etcd: etcdImg})
+}
+
+func preloadImageArchive(cmd *cobra.Command, ui *UI, clusterProvider providerpkg.Provider, clusterName, archive string) error {
+	if _, err := os.Stat(archive); err != nil {
+		return fmt.Errorf("image archive: %w", err)
+	}
+	return ui.Step("images: loading "+archive+" into "+clusterName, func() error {
+		return clusterProvider.LoadImageArchive(cmd.Context(), clusterName, archive)
+	})
+}
+
+// loadLocalImages side-loads images from the local Docker daemon. With
+// skipMissing, images the daemon does not have are reported and left for the
+// nodes to pull; otherwise they are an error.
+func loadLocalImages(cmd *cobra.Command, ui *UI, clusterProvider providerpkg.Provider, clusterName string, images []string, skipMissing bool) error {
+	ctx := cmd.Context()
+	var present, missing []string
+	seen := map[string]bool{}
+	for _, image := range images {
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+		if localImageExists(ctx, image) {
+			present = append(present, image)
+		} else {
+			missing = append(missing, image)
+		}
+	}
+	if len(missing) > 0 {
+		if !skipMissing {
+			return fmt.Errorf("images not found in the local Docker daemon: %s", strings.Join(missing, ", "))
+		}
+		logf := stepLogf(cmd, ui, "images")
+		for _, image := range missing {
+			logf("%s not built locally; nodes will pull it", image)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+	return ui.Step(fmt.Sprintf("images: loading %s into %s", strings.Join(present, ", "), clusterName), func() error {
+		return clusterProvider.LoadImages(ctx, clusterName, present)
+	})
+}
+
+func localImageExists(ctx context.Context, image string) bool {
+	return exec.CommandContext(ctx, "docker", "image", "inspect", image).Run() == nil
+}
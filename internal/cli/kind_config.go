@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	providerpkg "github.com/kplane-dev/kplane/internal/provider"
+	"sigs.k8s.io/yaml"
+)
+
+const kindAPIVersion = "kind.x-k8s.io/v1alpha4"
+
+// mergeKindConfig adds what kplane needs to a kind Cluster config: the ingress
+// port mapping and ingress-ready label on the control-plane node, worker nodes
+// for --ha, the etcd mounts on every node and the registry mirror patches.
+// Everything else in the user's config is kept. Settings that contradict
+// kplane's are reported instead of overwritten.
+func mergeKindConfig(raw []byte, ingressPort, workers int, mounts []providerpkg.Mount, mirrors []providerpkg.Mirror) ([]byte, error) {
+	cfg := map[string]any{}
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("parse kind config: %w", err)
+	}
+	if cfg == nil {
+		cfg = map[string]any{}
+	}
+	if kind, _ := cfg["kind"].(string); kind != "" && kind != "Cluster" {
+		return nil, fmt.Errorf("kind config has kind %q; expected Cluster", kind)
+	}
+	if apiVersion, _ := cfg["apiVersion"].(string); apiVersion != "" && apiVersion != kindAPIVersion {
+		return nil, fmt.Errorf("kind config has apiVersion %q; expected %s", apiVersion, kindAPIVersion)
+	}
+	cfg["kind"] = "Cluster"
+	cfg["apiVersion"] = kindAPIVersion
+
+	var nodes []map[string]any
+	if items, ok := cfg["nodes"].([]any); ok {
+		for i, item := range items {
+			node, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("kind config nodes[%d] is not a mapping", i)
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		nodes = []map[string]any{{"role": "control-plane"}}
+	}
+	var controlPlanes, existingWorkers []int
+	for i, node := range nodes {
+		switch role, _ := node["role"].(string); role {
+		case "", "control-plane":
+			controlPlanes = append(controlPlanes, i)
+		case "worker":
+			existingWorkers = append(existingWorkers, i)
+		}
+	}
+	if len(controlPlanes) != 1 {
+		return nil, fmt.Errorf("kind config has %d control-plane nodes; kplane publishes the ingress from exactly one", len(controlPlanes))
+	}
+	controlPlane := nodes[controlPlanes[0]]
+	if err := mergeKindPortMapping(controlPlane, ingressPort); err != nil {
+		return nil, err
+	}
+	if err := mergeKindLabel(controlPlane, "ingress-ready", "true"); err != nil {
+		return nil, err
+	}
+	for i := len(existingWorkers); i < workers; i++ {
+		nodes = append(nodes, map[string]any{"role": "worker"})
+	}
+	for i, node := range nodes {
+		if err := mergeKindMounts(node, mounts); err != nil {
+			return nil, fmt.Errorf("kind config nodes[%d]: %w", i, err)
+		}
+	}
+	items := make([]any, 0, len(nodes))
+	for _, node := range nodes {
+		items = append(items, node)
+	}
+	cfg["nodes"] = items
+
+	if len(mirrors) > 0 {
+		patches, _ := cfg["containerdConfigPatches"].([]any)
+		for _, mirror := range mirrors {
+			key := fmt.Sprintf("registry.mirrors.%q", mirror.Upstream)
+			want := fmt.Sprintf("[plugins.\"io.containerd.grpc.v1.cri\".%s]\n  endpoint = [%q]", key, mirror.Endpoint)
+			found := false
+			for _, patch := range patches {
+				text, _ := patch.(string)
+				switch {
+				case text == want:
+					found = true
+				case strings.Contains(text, key):
+					return nil, fmt.Errorf("kind config already sets a mirror for %s; drop it or use --registry-mirror=false", mirror.Upstream)
+				}
+			}
+			if !found {
+				patches = append(patches, want)
+			}
+		}
+		cfg["containerdConfigPatches"] = patches
+	}
+	return yaml.Marshal(cfg)
+}
+
+func mergeKindPortMapping(node map[string]any, ingressPort int) error {
+	mappings, _ := node["extraPortMappings"].([]any)
+	for _, item := range mappings {
+		mapping, _ := item.(map[string]any)
+		containerPort, hostPort := intValue(mapping["containerPort"]), intValue(mapping["hostPort"])
+		switch {
+		case containerPort == 443 && hostPort == ingressPort:
+			return nil
+		case containerPort == 443:
+			return fmt.Errorf("kind config maps the ingress port 443 to host port %d; set kind.ingressPort: %d or drop the mapping", hostPort, hostPort)
+		case hostPort == ingressPort:
+			return fmt.Errorf("kind config already uses host port %d for container port %d; kplane needs it for the ingress", hostPort, containerPort)
+		}
+	}
+	node["extraPortMappings"] = append(mappings, map[string]any{
+		"containerPort": 443,
+		"hostPort":      ingressPort,
+		"listenAddress": "127.0.0.1",
+	})
+	return nil
+}
+
+func mergeKindLabel(node map[string]any, key, value string) error {
+	labels, _ := node["labels"].(map[string]any)
+	if labels == nil {
+		labels = map[string]any{}
+	}
+	if current, ok := labels[key]; ok && fmt.Sprint(current) != value {
+		return fmt.Errorf("kind config labels the control-plane node %s=%v; kplane needs %s=%s", key, current, key, value)
+	}
+	labels[key] = value
+	node["labels"] = labels
+	return nil
+}
+
+func mergeKindMounts(node map[string]any, mounts []providerpkg.Mount) error {
+	if len(mounts) == 0 {
+		return nil
+	}
+	existing, _ := node["extraMounts"].([]any)
+	for _, mount := range mounts {
+		found := false
+		for _, item := range existing {
+			current, _ := item.(map[string]any)
+			if current["containerPath"] != mount.ContainerPath {
+				continue
+			}
+			if current["hostPath"] != mount.HostPath {
+				return fmt.Errorf("%s is mounted from %v; kplane mounts it from %s", mount.ContainerPath, current["hostPath"], mount.HostPath)
+			}
+			found = true
+		}
+		if !found {
+			existing = append(existing, map[string]any{"hostPath": mount.HostPath, "containerPath": mount.ContainerPath})
+		}
+	}
+	node["extraMounts"] = existing
+	return nil
+}
+
+func intValue(value any) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	providerpkg "github.com/kplane-dev/kplane/internal/provider"
+	"sigs.k8s.io/yaml"
+)
+
+func TestMergeKindConfig(t *testing.T) {
+	etcd := []providerpkg.Mount{{HostPath: "/home/dev/etcd", ContainerPath: "/var/lib/kplane/etcd"}}
+	docker := []providerpkg.Mirror{{Upstream: "docker.io", Endpoint: "http://kplane-mirror-docker-io:5000"}}
+	tests := []struct {
+		name    string
+		raw     string
+		workers int
+		mounts  []providerpkg.Mount
+		mirrors []providerpkg.Mirror
+		want    string
+		wantErr string
+	}{
+		{
+			name: "empty",
+			want: `
+apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- role: control-plane
+  labels: {ingress-ready: "true"}
+  extraPortMappings:
+  - {containerPort: 443, hostPort: 8443, listenAddress: 127.0.0.1}
+`,
+		},
+		{
+			name: "user settings kept",
+			raw: `
+kind: Cluster
+name: dev
+networking: {podSubnet: 10.240.0.0/16}
+nodes:
+- role: control-plane
+  image: kindest/node:v1.31.0
+  labels: {team: platform}
+  extraPortMappings:
+  - {containerPort: 80, hostPort: 8080}
+- role: worker
+`,
+			want: `
+apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+name: dev
+networking: {podSubnet: 10.240.0.0/16}
+nodes:
+- role: control-plane
+  image: kindest/node:v1.31.0
+  labels: {team: platform, ingress-ready: "true"}
+  extraPortMappings:
+  - {containerPort: 80, hostPort: 8080}
+  - {containerPort: 443, hostPort: 8443, listenAddress: 127.0.0.1}
+- role: worker
+`,
+		},
+		{
+			name:    "workers mounts and mirrors",
+			raw:     "nodes:\n- role: control-plane\n- role: worker\n",
+			workers: 2,
+			mounts:  etcd,
+			mirrors: docker,
+			want: `
+apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- role: control-plane
+  labels: {ingress-ready: "true"}
+  extraPortMappings:
+  - {containerPort: 443, hostPort: 8443, listenAddress: 127.0.0.1}
+  extraMounts:
+  - {hostPath: /home/dev/etcd, containerPath: /var/lib/kplane/etcd}
+- role: worker
+  extraMounts:
+  - {hostPath: /home/dev/etcd, containerPath: /var/lib/kplane/etcd}
+- role: worker
+  extraMounts:
+  - {hostPath: /home/dev/etcd, containerPath: /var/lib/kplane/etcd}
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+    endpoint = ["http://kplane-mirror-docker-io:5000"]
+`,
+		},
+		{
+			name:   "existing mount and patches kept",
+			mounts: etcd,
+			raw: `
+nodes:
+- role: control-plane
+  extraMounts:
+  - {hostPath: /home/dev/etcd, containerPath: /var/lib/kplane/etcd, readOnly: false}
+  - {hostPath: /src, containerPath: /src}
+containerdConfigPatches:
+- '[plugins."io.containerd.grpc.v1.cri".registry.mirrors."ghcr.io"]'
+`,
+			mirrors: docker,
+			want: `
+apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- role: control-plane
+  labels: {ingress-ready: "true"}
+  extraPortMappings:
+  - {containerPort: 443, hostPort: 8443, listenAddress: 127.0.0.1}
+  extraMounts:
+  - {hostPath: /home/dev/etcd, containerPath: /var/lib/kplane/etcd, readOnly: false}
+  - {hostPath: /src, containerPath: /src}
+containerdConfigPatches:
+- '[plugins."io.containerd.grpc.v1.cri".registry.mirrors."ghcr.io"]'
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+    endpoint = ["http://kplane-mirror-docker-io:5000"]
+`,
+		},
+		{
+			name: "matching ingress mapping kept",
+			raw:  "nodes:\n- role: control-plane\n  extraPortMappings:\n  - {containerPort: 443, hostPort: 8443}\n",
+			want: `
+apiVersion: kind.x-k8s.io/v1alpha4
+kind: Cluster
+nodes:
+- role: control-plane
+  labels: {ingress-ready: "true"}
+  extraPortMappings:
+  - {containerPort: 443, hostPort: 8443}
+`,
+		},
+		{
+			name:    "no control plane",
+			raw:     "nodes:\n- role: worker\n",
+			wantErr: "has 0 control-plane nodes",
+		},
+		{
+			name:    "two control planes",
+			raw:     "nodes:\n- role: control-plane\n- {}\n",
+			wantErr: "has 2 control-plane nodes",
+		},
+		{
+			name:    "ingress on another host port",
+			raw:     "nodes:\n- role: control-plane\n  extraPortMappings:\n  - {containerPort: 443, hostPort: 9443}\n",
+			wantErr: "maps the ingress port 443 to host port 9443",
+		},
+		{
+			name:    "host port taken",
+			raw:     "nodes:\n- role: control-plane\n  extraPortMappings:\n  - {containerPort: 80, hostPort: 8443}\n",
+			wantErr: "already uses host port 8443 for container port 80",
+		},
+		{
+			name:    "conflicting label",
+			raw:     "nodes:\n- role: control-plane\n  labels: {ingress-ready: \"false\"}\n",
+			wantErr: "ingress-ready=false",
+		},
+		{
+			name:    "conflicting mount",
+			raw:     "nodes:\n- role: control-plane\n- role: worker\n  extraMounts:\n  - {hostPath: /tmp/etcd, containerPath: /var/lib/kplane/etcd}\n",
+			mounts:  etcd,
+			wantErr: "nodes[1]: /var/lib/kplane/etcd is mounted from /tmp/etcd",
+		},
+		{
+			name:    "conflicting mirror",
+			raw:     "containerdConfigPatches:\n- '[plugins.\"io.containerd.grpc.v1.cri\".registry.mirrors.\"docker.io\"]'\n",
+			mirrors: docker,
+			wantErr: "already sets a mirror for docker.io",
+		},
+		{
+			name:    "wrong kind",
+			raw:     "kind: Config\n",
+			wantErr: `has kind "Config"`,
+		},
+		{
+			name:    "wrong apiVersion",
+			raw:     "apiVersion: kind.x-k8s.io/v1alpha3\n",
+			wantErr: `has apiVersion "kind.x-k8s.io/v1alpha3"`,
+		},
+		{
+			name:    "node not a mapping",
+			raw:     "nodes:\n- control-plane\n",
+			wantErr: "nodes[0] is not a mapping",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeKindConfig([]byte(tt.raw), 8443, tt.workers, tt.mounts, tt.mirrors)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("mergeKindConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameYAML(t, got, tt.want)
+
+			// Merging the result again must not change it.
+			again, err := mergeKindConfig(got, 8443, tt.workers, tt.mounts, tt.mirrors)
+			if err != nil {
+				t.Fatalf("merging the output again: %v", err)
+			}
+			assertSameYAML(t, again, string(got))
+		})
+	}
+}
+
+func assertSameYAML(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue any
+	if err := yaml.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
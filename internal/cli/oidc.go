@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/kplane-dev/kplane/internal/config"
+	"github.com/kplane-dev/kplane/internal/kubeconfig"
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"github.com/kplane-dev/kplane/internal/stack"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newOIDCCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "oidc",
+		Short: "Run a local OIDC issuer for testing VCP SSO",
+	}
+	cmd.AddCommand(newOIDCServeCommand(), newOIDCStopCommand())
+	return cmd
+}
+
+func newOIDCServeCommand() *cobra.Command {
+	var (
+		issuerURL     string
+		clientID      string
+		image         string
+		users         []string
+		namespace     string
+		managementCtx string
+		quiet         bool
+		noColor       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Deploy dex with static users behind the ingress at /oidc",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			staticUsers, err := parseStaticUsers(users)
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			if installed, ok := installedStack(ctx, managementCtx, namespace); ok {
+				if err := requireStackFeature(installed, stack.FeatureOIDC, "kplane oidc serve"); err != nil {
+					return err
+				}
+			}
+			if issuerURL == "" {
+				issuerURL = externalBaseURL(ctx, managementCtx, namespace) + "/oidc"
+			}
+
+			ui := NewUI(cmd.OutOrStdout(), profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			installOpts, err := oidcInstallOptions(profile, managementCtx, namespace, stepLogf(cmd, ui, "oidc"))
+			if err != nil {
+				return err
+			}
+			if err := ui.Step("oidc: deploying issuer", func() error {
+				return stacklatest.ServeOIDC(ctx, stacklatest.OIDCServeOptions{
+					InstallOptions: installOpts,
+					IssuerURL:      issuerURL,
+					ClientID:       clientID,
+					Image:          image,
+					Users:          staticUsers,
+				})
+			}); err != nil {
+				return err
+			}
+			if err := recordManagementValue(ctx, managementCtx, namespace, oidcLoopbackKey, stacklatest.OIDCLoopbackPort(issuerURL)); err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "issuer: %s\n", issuerURL)
+			fmt.Fprintln(out, "next: create a VCP that trusts it, then fetch an OIDC kubeconfig (requires kubelogin)")
+			fmt.Fprintf(out, "  kplane create cluster <name> --oidc-issuer-url %s --oidc-client-id %s --oidc-username-claim email\n", issuerURL, clientID)
+			fmt.Fprintln(out, "  kplane get-credentials <name>")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&issuerURL, "issuer", "", "Issuer URL (default: <external URL>/oidc)")
+	cmd.Flags().StringVar(&clientID, "client-id", stacklatest.DefaultOIDCClientID, "OIDC client ID registered with the issuer")
+	cmd.Flags().StringVar(&image, "image", stacklatest.DefaultDexImage, "Dex image")
+	cmd.Flags().StringArrayVar(&users, "user", nil, "Static user as email:password (repeatable)")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
+
+func newOIDCStopCommand() *cobra.Command {
+	var (
+		namespace     string
+		managementCtx string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Remove the local OIDC issuer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			installOpts, err := oidcInstallOptions(profile, managementCtx, namespace, nil)
+			if err != nil {
+				return err
+			}
+			if err := stacklatest.StopOIDC(cmd.Context(), installOpts); err != nil {
+				return err
+			}
+			if err := recordManagementValue(cmd.Context(), managementCtx, namespace, oidcLoopbackKey, ""); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "removed oidc issuer")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	return cmd
+}
+
+// oidcLoopbackKey records in the management state that the apiserver carries
+// the loopback forwarder, so up and upgrade keep rendering it.
+const oidcLoopbackKey = "oidcLoopbackPort"
+
+// oidcInstallOptions carries the profile settings the apiserver is rendered
+// with; the running apiserver image is kept.
+func oidcInstallOptions(profile config.Profile, managementCtx, namespace string, logf func(string, ...any)) (stacklatest.InstallOptions, error) {
+	authzMode, err := stacklatest.NormalizeAuthorizationMode(profile.AuthorizationMode)
+	if err != nil {
+		return stacklatest.InstallOptions{}, err
+	}
+	return stacklatest.InstallOptions{
+		Context:           managementCtx,
+		Namespace:         namespace,
+		HA:                profile.HA,
+		AuthorizationMode: authzMode,
+		Logf:              logf,
+	}, nil
+}
+
+func parseStaticUsers(values []string) ([]stacklatest.StaticUser, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("at least one --user email:password is required")
+	}
+	users := make([]stacklatest.StaticUser, 0, len(values))
+	for _, value := range values {
+		email, password, ok := strings.Cut(value, ":")
+		if !ok || email == "" || password == "" {
+			return nil, fmt.Errorf("invalid --user %q (use email:password)", value)
+		}
+		users = append(users, stacklatest.StaticUser{Email: email, Password: password})
+	}
+	return users, nil
+}
+
+// controlPlaneOIDC returns the OIDC settings of the class a VCP uses, or nil.
+func controlPlaneOIDC(ctx context.Context, managementCtx, name string) (*stacklatest.OIDC, error) {
+	controlPlane, err := kubectl.Get(ctx, managementCtx, "controlplane", name, "")
+	if err != nil {
+		return nil, err
+	}
+	className, _, _ := unstructured.NestedString(controlPlane.Object, "spec", "classRef", "name")
+	if className == "" {
+		return nil, nil
+	}
+	class, err := kubectl.Get(ctx, managementCtx, "controlplaneclass", className, "")
+	if err != nil {
+		return nil, err
+	}
+	auth, _, _ := unstructured.NestedMap(class.Object, "spec", "auth")
+	return stacklatest.OIDCFromSpec(auth)
+}
+
+// oidcKubeconfig writes a VCP context whose user runs kubelogin
+// (`kubectl oidc-login get-token`) against the class issuer.
+func oidcKubeconfig(ctx context.Context, managementCtx, namespace, name, server string, oidc *stacklatest.OIDC, clientSecret string, scopes []string) ([]byte, error) {
+	caPEM, err := kubectl.GetSecretData(ctx, managementCtx, "kplane-cluster-signing-keys", namespace, "ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("read management plane CA: %w", err)
+	}
+	args := []string{
+		"oidc-login",
+		"get-token",
+		"--oidc-issuer-url=" + oidc.IssuerURL,
+		"--oidc-client-id=" + oidc.ClientID,
+	}
+	for _, scope := range scopes {
+		args = append(args, "--oidc-extra-scope="+scope)
+	}
+	if clientSecret != "" {
+		args = append(args, "--oidc-client-secret="+clientSecret)
+	}
+	if len(oidc.CAData) > 0 {
+		args = append(args, "--certificate-authority-data="+base64.StdEncoding.EncodeToString(oidc.CAData))
+	}
+	return kubeconfig.NewExecConfig("kplane-"+name, server, caPEM, &clientcmdapi.ExecConfig{
+		APIVersion:      "client.authentication.k8s.io/v1beta1",
+		Command:         "kubectl",
+		Args:            args,
+		InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+	})
+}
+
+func isLoopbackURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	return host == "localhost" || strings.HasPrefix(host, "127.")
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/kplane-dev/kplane/internal/config"
+	"github.com/kplane-dev/kplane/internal/kubectl"
 	"github.com/spf13/cobra"
 )
 
@@ -23,9 +24,24 @@ func Execute() error {
 		newDownCommand(),
 		newCreateCommand(),
 		newCreateClusterAliasCommand(),
+		newDeleteCommand(),
+		newDeleteClusterAliasCommand(),
 		newConfigCommand(),
 		newGetCommand(),
+		newDescribeCommand(),
 		newGetCredentialsCommand(),
+		newCertsCommand(),
+		newCredentialsCommand(),
+		newOIDCCommand(),
+		newUpgradeCommand(),
+		newBackupCommand(),
+		newRestoreCommand(),
+		newExportCommand(),
+		newImportCommand(),
+		newCloneCommand(),
+		newStackCommand(),
+		newImagesCommand(),
+		newDevCommand(),
 		newDoctorCommand(),
 	)
 
@@ -43,17 +59,20 @@ func loadConfig() (config.Config, error) {
 		return config.Config{}, err
 	}
 
-	updated, changed := config.ApplyDefaultImageUpgrades(cfg)
-	if changed {
-		if err := config.Save(path, updated); err != nil {
-			return config.Config{}, err
-		}
-		cfg = updated
+	if profile, err := cfg.ActiveProfile(); err == nil {
+		useProviderKubeconfig(profile.Provider, profile)
 	}
-
 	return cfg, nil
 }
 
+// useProviderKubeconfig points the management plane client at the kubeconfig
+// the kubeconfig provider is configured with.
+func useProviderKubeconfig(providerName string, profile config.Profile) {
+	if providerName == "kubeconfig" {
+		kubectl.SetKubeconfigPath(profile.Kubeconfig.Path)
+	}
+}
+
 func mustConfig() config.Config {
 	cfg, err := loadConfig()
 	if err != nil {
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kplane-dev/kplane/internal/stacks"
+	"github.com/spf13/cobra"
+)
+
+func newStackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stack",
+		Short: "Inspect the management plane stacks this binary supports",
+	}
+	cmd.AddCommand(newStackListCommand())
+	return cmd
+}
+
+func newStackListCommand() *cobra.Command {
+	var wide bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List supported stack versions and their pinned images",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			selected, err := stacks.Get(profile.StackVersion)
+			if err != nil {
+				return err
+			}
+			newest := stacks.List()[0].Version
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			if wide {
+				fmt.Fprintln(w, "VERSION\tAPISERVER\tOPERATOR\tETCD\tCRDS\tFEATURES")
+			} else {
+				fmt.Fprintln(w, "VERSION\tAPISERVER\tOPERATOR\tETCD\tDESCRIPTION")
+			}
+			for _, s := range stacks.List() {
+				version := s.Version
+				if version == newest {
+					version += " (" + stacks.DefaultVersion + ")"
+				}
+				if s.Version == selected.Version {
+					version += " *"
+				}
+				if wide {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", version, s.Images.Apiserver, s.Images.Operator, s.Images.Etcd, s.CRDSource, strings.Join(s.Features, ","))
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", version, s.Images.Apiserver, s.Images.Operator, s.Images.Etcd, s.Description)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "* selected by the active profile (stackVersion)")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&wide, "wide", false, "Show CRD sources and feature flags")
+	return cmd
+}
@@ -2,17 +2,27 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/kplane-dev/kplane/internal/config"
 	"github.com/kplane-dev/kplane/internal/kubeconfig"
 	"github.com/kplane-dev/kplane/internal/kubectl"
 	providerpkg "github.com/kplane-dev/kplane/internal/provider"
 	"github.com/kplane-dev/kplane/internal/providers"
+	"github.com/kplane-dev/kplane/internal/registry"
+	"github.com/kplane-dev/kplane/internal/stack"
 	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/kplane-dev/kplane/internal/stacks"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func newUpCommand() *cobra.Command {
@@ -26,6 +36,20 @@ func newUpCommand() *cobra.Command {
 		stackVersion  string
 		crdSource     string
 		installCRDs   bool
+		regenPKI      bool
+		tlsSANs       []string
+		authzMode     string
+		authPolicy    string
+		issuerTmpl    string
+		etcdStorage   string
+		etcdSize      string
+		etcdClass     string
+		etcdHostPath  string
+		ha            bool
+		imageArchive  string
+		loadLocal     bool
+		mirror        bool
+		externalURL   string
 		kubeconfigOut string
 		setCurrent    bool
 		quiet         bool
@@ -49,74 +73,100 @@ func newUpCommand() *cobra.Command {
 
 			applyUpDefaults(&provider, &clusterName, &namespace, &apiserverImg, &operatorImg, &etcdImg, &stackVersion, &crdSource, &kubeconfigOut, &setCurrent, profile)
 
-			clusterProvider, err := providers.New(provider)
+			clusterProvider, err := providers.NewForProfile(provider, profile)
 			if err != nil {
 				return err
 			}
 			if err := clusterProvider.EnsureInstalled(); err != nil {
 				return err
 			}
-			if err := kubectl.EnsureInstalled(); err != nil {
-				return err
-			}
 
 			ctx := cmd.Context()
-			exists, err := clusterProvider.ClusterExists(ctx, clusterName)
+			providerName := clusterProvider.Name()
+			useProviderKubeconfig(providerName, profile)
+			storage, mounts, err := resolveEtcdStorage(providerName, clusterName, etcdStorage, etcdSize, etcdClass, etcdHostPath, profile)
 			if err != nil {
 				return err
 			}
-			var ingressPort int
-			providerName := clusterProvider.Name()
-			if !exists {
-				if err := ui.Step(providerName+": creating management cluster "+clusterName, func() error {
-					var err error
-					ingressPort, err = resolveIngressPort(resolveIngressPortSetting(providerName, profile))
-					if err != nil {
-						return err
-					}
-					createOpts, err := buildCreateOptions(providerName, profile, ingressPort)
-					if err != nil {
-						return err
-					}
-					return clusterProvider.CreateCluster(ctx, providerpkg.CreateClusterOptions{
-						Name:        clusterName,
-						NodeImage:   createOpts.NodeImage,
-						ConfigPath:  createOpts.ConfigPath,
-						IngressPort: ingressPort,
-					})
-				}); err != nil {
+			if !cmd.Flags().Changed("ha") {
+				ha = profile.HA
+			}
+			if !cmd.Flags().Changed("registry-mirror") {
+				mirror = profile.Registry.Mirror
+			}
+			if externalURL == "" {
+				externalURL = profile.Kubeconfig.ExternalURL
+			}
+			externalHost, err := resolveExternalHost(providerName, externalURL)
+			if err != nil {
+				return err
+			}
+			workers := 0
+			if ha {
+				workers = stacklatest.HAMembers - 1
+			}
+			contextName, ingressPort, err := ensureManagementCluster(ctx, cmd, ui, profile, managementClusterSpec{
+				Provider:   clusterProvider,
+				Name:       clusterName,
+				Namespace:  namespace,
+				Workers:    workers,
+				Mounts:     mounts,
+				Kubeconfig: kubeconfigOut,
+				SetCurrent: setCurrent,
+				Mirrors:    resolveMirrors(providerName, mirror, profile),
+			})
+			if err != nil {
+				return err
+			}
+			if imageArchive != "" {
+				if err := preloadImageArchive(cmd, ui, clusterProvider, clusterName, imageArchive); err != nil {
 					return err
 				}
-			} else {
-				if ui.Enabled() {
-					ui.Infof("%s: reusing existing cluster %s", providerName, clusterName)
-				}
-				ingressPort = resolveIngressPortFromCluster(ctx, clusterProvider.ContextName(clusterName), namespace)
 			}
-
-			if err := ui.Step("kubeconfig: updating", func() error {
-				kubeconfigData, err := clusterProvider.GetKubeconfig(ctx, clusterName)
-				if err != nil {
+			resolvedStack, err := resolveStack(stackVersion)
+			if err != nil {
+				return err
+			}
+			applyStackDefaults(resolvedStack, &apiserverImg, &operatorImg, &etcdImg, &crdSource)
+			if loadLocal {
+				if err := loadLocalImages(cmd, ui, clusterProvider, clusterName, []string{apiserverImg, operatorImg}, true); err != nil {
 					return err
 				}
-				return kubeconfig.MergeAndWrite(kubeconfigOut, kubeconfigData, setCurrent)
-			}); err != nil {
-				return err
 			}
 
-			contextName := clusterProvider.ContextName(clusterName)
-			if err := ui.Step("nodes: labeling ingress-ready", func() error {
-				return kubectl.LabelNodes(ctx, contextName, map[string]string{"ingress-ready": "true"})
-			}); err != nil {
-				return err
+			if authPolicy == "" {
+				authPolicy = profile.Auth.Policy
 			}
-			resolvedVersion, err := resolveStackVersion(stackVersion)
+			if issuerTmpl == "" {
+				issuerTmpl = profile.Auth.IssuerTemplate
+			}
+
+			if authzMode == "" {
+				authzMode = profile.AuthorizationMode
+			}
+			authzMode, err = stacklatest.NormalizeAuthorizationMode(authzMode)
 			if err != nil {
 				return err
 			}
+			if authzMode == stacklatest.AuthorizationModeAlwaysAllow {
+				fmt.Fprintln(cmd.ErrOrStderr(), "warning: --authorization-mode=always-allow lets any caller on the ingress port act on every VCP")
+			}
+			if err := checkInstallFeatures(resolvedStack, stacklatest.Auth{Policy: authPolicy}, authzMode); err != nil {
+				return err
+			}
+			for _, note := range stackPinNotes(profile, resolvedStack) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", note)
+			}
+			for _, note := range devReloadNotes(reloadedImages(cmd.Context(), contextName, namespace), stacklatest.Images{Apiserver: apiserverImg, Operator: operatorImg}) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", note)
+			}
+
+			if regenPKI {
+				fmt.Fprintln(cmd.ErrOrStderr(), "warning: --regenerate-pki replaces the management plane CA; existing VCP kubeconfigs will stop working")
+			}
 
-			switch resolvedVersion {
-			case "latest":
+			switch resolvedStack.Manifests {
+			case stacklatest.Name:
 				if err := ui.Step("stack: installing management plane", func() error {
 					return stacklatest.Install(cmd.Context(), stacklatest.InstallOptions{
 						Context:   contextName,
@@ -126,25 +176,32 @@ func newUpCommand() *cobra.Command {
 							Operator:  operatorImg,
 							Etcd:      etcdImg,
 						},
+						EtcdStorage: storage,
+						HA:          ha,
 						CRDSource:   crdSource,
 						InstallCRDs: installCRDs,
-						Logf: func(format string, args ...any) {
-							msg := fmt.Sprintf(format, args...)
-							if ui.Enabled() {
-								ui.Infof("stack: %s", msg)
-							} else {
-								fmt.Fprintf(cmd.OutOrStdout(), "stack: %s\n", msg)
-							}
+						Auth: stacklatest.Auth{
+							Policy:         authPolicy,
+							IssuerTemplate: issuerTmpl,
 						},
+						AuthorizationMode:   authzMode,
+						TLSSANs:             appendSAN(append(append([]string{}, profile.TLSSANs...), tlsSANs...), externalHost),
+						IngressLoadBalancer: providerName == "kubeconfig",
+						OIDCLoopbackPort:    managementValue(cmd.Context(), contextName, namespace, oidcLoopbackKey),
+						RegeneratePKI:       regenPKI,
+						Logf:                stepLogf(cmd, ui, "stack"),
 					})
 				}); err != nil {
 					return err
 				}
 				if err := ui.Step("ingress: recording port", func() error {
-					return applyIngressConfig(cmd.Context(), contextName, namespace, ingressPort)
+					return recordManagementState(cmd.Context(), contextName, namespace, ingressPort, externalURL, resolvedStack.Version)
 				}); err != nil {
 					return err
 				}
+				if err := clearReloadedImages(cmd.Context(), contextName, namespace); err != nil {
+					return err
+				}
 				if ui.Enabled() {
 					ui.Successf("ready: management plane is up")
 				} else {
@@ -167,7 +224,7 @@ func newUpCommand() *cobra.Command {
 				_ = markUICompletion(cfg, true, false)
 				return nil
 			default:
-				return fmt.Errorf("unsupported stack version %q", resolvedVersion)
+				return fmt.Errorf("stack %s uses unsupported manifests %q", resolvedStack.Version, resolvedStack.Manifests)
 			}
 		},
 	}
@@ -179,8 +236,22 @@ func newUpCommand() *cobra.Command {
 	cmd.Flags().StringVar(&operatorImg, "operator-image", "", "Controlplane-operator image")
 	cmd.Flags().StringVar(&etcdImg, "etcd-image", "", "Etcd image")
 	cmd.Flags().StringVar(&stackVersion, "stack-version", "", "Stack version to install")
-	cmd.Flags().StringVar(&crdSource, "crd-source", "", "CRD source (kustomize URL or path)")
+	cmd.Flags().StringVar(&etcdStorage, "etcd-storage", "", "Etcd storage: pvc or hostpath (default: pvc)")
+	cmd.Flags().StringVar(&etcdSize, "etcd-storage-size", "", "Etcd volume size (default: "+stacklatest.DefaultEtcdStorageSize+")")
+	cmd.Flags().StringVar(&etcdClass, "etcd-storage-class", "", "StorageClass for the etcd volume (default: cluster default)")
+	cmd.Flags().StringVar(&etcdHostPath, "etcd-host-path", "", "Host directory mounted into the node for --etcd-storage hostpath")
+	cmd.Flags().BoolVar(&ha, "ha", false, "Run a three-member etcd and three apiserver replicas on a multi-node cluster")
+	cmd.Flags().StringVar(&imageArchive, "image-archive", "", "Load images from a docker save archive into the new cluster before installing (offline installs)")
+	cmd.Flags().BoolVar(&mirror, "registry-mirror", false, "Pull through local registry caches that survive kplane down (default: profile registry.mirror)")
+	cmd.Flags().BoolVar(&loadLocal, "load-local-images", false, "Copy the apiserver and operator images from the local Docker daemon into the cluster (skips images not built locally)")
+	cmd.Flags().StringVar(&crdSource, "crd-source", "", "CRD source: embedded, or a kustomize URL or path")
 	cmd.Flags().BoolVar(&installCRDs, "install-crds", true, "Install CRDs before deploying operator")
+	cmd.Flags().StringVar(&authPolicy, "auth-policy", "", "Auth policy for the default ControlPlaneClass: managed (default, ManagedIssuer) or basic")
+	cmd.Flags().StringVar(&issuerTmpl, "issuer-template", "", "Issuer URL template for the managed auth policy (e.g. https://{externalHost})")
+	cmd.Flags().StringVar(&authzMode, "authorization-mode", "", "Shared apiserver authorization: rbac or always-allow (default: rbac)")
+	cmd.Flags().StringVar(&externalURL, "external-url", "", "URL clients use to reach the ingress of a kubeconfig-provider cluster, e.g. https://kplane.example.com (default: profile kubeconfig.externalURL)")
+	cmd.Flags().StringSliceVar(&tlsSANs, "tls-san", nil, "Extra DNS name or IP for the apiserver and ingress certificates (repeatable)")
+	cmd.Flags().BoolVar(&regenPKI, "regenerate-pki", false, "Discard the stored CA, keys and admin token and issue new ones")
 	cmd.Flags().StringVar(&kubeconfigOut, "kubeconfig", "", "Kubeconfig path to update")
 	cmd.Flags().BoolVar(&setCurrent, "set-current", true, "Set current kubeconfig context")
 	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
@@ -229,23 +300,273 @@ func resolveIngressPortSetting(providerName string, profile config.Profile) int
 	switch providerName {
 	case "k3s":
 		return profile.K3s.IngressPort
+	case "kubeconfig":
+		return 0
 	default:
 		return profile.Kind.IngressPort
 	}
 }
 
-func buildCreateOptions(providerName string, profile config.Profile, ingressPort int) (createOptions, error) {
+type managementClusterSpec struct {
+	Provider   providerpkg.Provider
+	Name       string
+	Namespace  string
+	Workers    int
+	Mounts     []providerpkg.Mount
+	Kubeconfig string
+	SetCurrent bool
+	// IngressPort overrides the profile's ingress port for a new cluster.
+	IngressPort int
+	// Mirrors are started and attached to the cluster's network; a new
+	// cluster pulls through them.
+	Mirrors []registry.Mirror
+}
+
+// ensureManagementCluster creates the management cluster when it is missing,
+// merges its kubeconfig and labels the ingress node. It returns the cluster's
+// context and the host port the ingress is published on.
+func ensureManagementCluster(ctx context.Context, cmd *cobra.Command, ui *UI, profile config.Profile, spec managementClusterSpec) (string, int, error) {
+	clusterProvider := spec.Provider
+	providerName := clusterProvider.Name()
+	contextName := clusterProvider.ContextName(spec.Name)
+	exists, err := clusterProvider.ClusterExists(ctx, spec.Name)
+	if err != nil {
+		return "", 0, err
+	}
+	if exists && len(spec.Mounts) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: cluster %s already exists, so %s is not mounted from %s; etcd data stays on the node\n", spec.Name, stacklatest.EtcdNodePath, spec.Mounts[0].HostPath)
+	}
+	if exists && len(spec.Mirrors) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: cluster %s already exists, so its nodes are not configured to pull through the registry mirrors; recreate it with kplane down and kplane up to use them\n", spec.Name)
+	}
+	if len(spec.Mirrors) > 0 {
+		if err := ui.Step(fmt.Sprintf("registry: starting %d mirror(s)", len(spec.Mirrors)), func() error {
+			return registry.Ensure(ctx, spec.Mirrors)
+		}); err != nil {
+			return "", 0, err
+		}
+	}
+	mirrors := make([]providerpkg.Mirror, 0, len(spec.Mirrors))
+	for _, mirror := range spec.Mirrors {
+		mirrors = append(mirrors, providerpkg.Mirror{Upstream: mirror.Upstream, Endpoint: mirror.Endpoint()})
+	}
+	var ingressPort int
+	if !exists {
+		if err := ui.Step(providerName+": creating management cluster "+spec.Name, func() error {
+			requested := spec.IngressPort
+			if requested == 0 {
+				requested = resolveIngressPortSetting(providerName, profile)
+			}
+			var err error
+			ingressPort, err = resolveIngressPort(requested)
+			if err != nil {
+				return err
+			}
+			createOpts, err := buildCreateOptions(providerName, profile, ingressPort, spec.Workers, spec.Mounts, mirrors)
+			if err != nil {
+				return err
+			}
+			return clusterProvider.CreateCluster(ctx, providerpkg.CreateClusterOptions{
+				Name:        spec.Name,
+				NodeImage:   createOpts.NodeImage,
+				ConfigPath:  createOpts.ConfigPath,
+				IngressPort: ingressPort,
+				Workers:     spec.Workers,
+				Mounts:      spec.Mounts,
+				Mirrors:     mirrors,
+			})
+		}); err != nil {
+			return "", 0, err
+		}
+	} else {
+		if ui.Enabled() {
+			ui.Infof("%s: reusing existing cluster %s", providerName, spec.Name)
+		}
+		ingressPort = resolveIngressPortFromCluster(ctx, contextName, spec.Namespace)
+	}
+
+	if len(spec.Mirrors) > 0 {
+		network := clusterNetwork(providerName, spec.Name)
+		if err := ui.Step("registry: attaching mirrors to "+network, func() error {
+			return registry.Connect(ctx, network, spec.Mirrors)
+		}); err != nil {
+			return "", 0, err
+		}
+	}
+
+	if err := ui.Step("kubeconfig: updating", func() error {
+		kubeconfigData, err := clusterProvider.GetKubeconfig(ctx, spec.Name)
+		if err != nil {
+			return err
+		}
+		if err := kubeconfig.MergeAndWrite(spec.Kubeconfig, kubeconfigData, spec.SetCurrent); err != nil {
+			return err
+		}
+		kubectl.Forget(contextName)
+		return nil
+	}); err != nil {
+		return "", 0, err
+	}
+
+	if providerName == "kubeconfig" {
+		return contextName, ingressPort, nil
+	}
+	if err := ui.Step("nodes: labeling ingress-ready", func() error {
+		return kubectl.LabelNodes(ctx, contextName, ingressNodeSelector, map[string]string{"ingress-ready": "true"})
+	}); err != nil {
+		return "", 0, err
+	}
+	return contextName, ingressPort, nil
+}
+
+// stepLogf prints stack progress under the current UI step.
+func stepLogf(cmd *cobra.Command, ui *UI, prefix string) func(format string, args ...any) {
+	return func(format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if ui.Enabled() {
+			ui.Infof("%s: %s", prefix, msg)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", prefix, msg)
+		}
+	}
+}
+
+// recordManagementState writes the ingress port and the installed stack
+// version to the kplane-management ConfigMap.
+func recordManagementState(ctx context.Context, contextName, namespace string, ingressPort int, externalURL, stackVersion string) error {
+	if err := applyIngressConfig(ctx, contextName, namespace, ingressPort, externalURL); err != nil {
+		return err
+	}
+	return recordStackVersion(ctx, contextName, namespace, stackVersion)
+}
+
+// resolveEtcdStorage merges etcd storage flags with the profile. The hostpath
+// mode on kind and k3d mounts a host directory into the node so etcd data
+// survives deleting the cluster.
+func resolveEtcdStorage(providerName, clusterName, mode, size, class, hostPath string, profile config.Profile) (stacklatest.EtcdStorage, []providerpkg.Mount, error) {
+	if mode == "" {
+		mode = profile.Etcd.Storage
+	}
+	if size == "" {
+		size = profile.Etcd.Size
+	}
+	if class == "" {
+		class = profile.Etcd.StorageClass
+	}
+	if hostPath == "" {
+		hostPath = profile.Etcd.HostPath
+	}
+	storage, err := stacklatest.NormalizeEtcdStorage(stacklatest.EtcdStorage{Mode: mode, Size: size, StorageClass: class})
+	if err != nil {
+		return stacklatest.EtcdStorage{}, nil, err
+	}
+	if storage.Mode != stacklatest.EtcdStorageHostPath || providerName == "kubeconfig" {
+		return storage, nil, nil
+	}
+	if hostPath == "" {
+		dataDir, err := defaultDataDir()
+		if err != nil {
+			return stacklatest.EtcdStorage{}, nil, err
+		}
+		hostPath = filepath.Join(dataDir, clusterName, "etcd")
+	}
+	hostPath, err = filepath.Abs(hostPath)
+	if err != nil {
+		return stacklatest.EtcdStorage{}, nil, fmt.Errorf("resolve etcd host path: %w", err)
+	}
+	if err := os.MkdirAll(hostPath, 0o700); err != nil {
+		return stacklatest.EtcdStorage{}, nil, fmt.Errorf("create etcd host path: %w", err)
+	}
+	return storage, []providerpkg.Mount{{HostPath: hostPath, ContainerPath: stacklatest.EtcdNodePath}}, nil
+}
+
+func defaultDataDir() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "kplane"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve data dir: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "kplane"), nil
+}
+
+// ingressNodeSelector picks the nodes that receive the ingress-ready label.
+// Local clusters map the ingress port to the control-plane node only, so
+// workers must not attract the controller. Shared clusters are never labeled;
+// their ingress is published through a LoadBalancer Service.
+const ingressNodeSelector = "node-role.kubernetes.io/control-plane"
+
+// resolveExternalHost validates the external URL of a kubeconfig-provider
+// cluster, which has no local port mapping to fall back to, and returns its
+// host for the ingress certificate.
+func resolveExternalHost(providerName, externalURL string) (string, error) {
+	if externalURL == "" {
+		if providerName == "kubeconfig" {
+			return "", fmt.Errorf("the kubeconfig provider needs --external-url (or kubeconfig.externalURL in the profile) pointing at the cluster's ingress")
+		}
+		return "", nil
+	}
+	parsed, err := url.Parse(externalURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return "", fmt.Errorf("external URL %q must be an https:// URL with a host", externalURL)
+	}
+	return parsed.Hostname(), nil
+}
+
+func appendSAN(sans []string, host string) []string {
+	if host == "" {
+		return sans
+	}
+	for _, san := range sans {
+		if san == host {
+			return sans
+		}
+	}
+	return append(sans, host)
+}
+
+// resolveMirrors returns the registry mirrors for a local cluster, or nil when
+// they are off or the cluster is not one kplane creates.
+func resolveMirrors(providerName string, enabled bool, profile config.Profile) []registry.Mirror {
+	if !enabled || providerName == "kubeconfig" {
+		return nil
+	}
+	return registry.Mirrors(profile.Registry.Upstreams)
+}
+
+// clusterNetwork is the Docker network the provider attaches nodes to.
+func clusterNetwork(providerName, clusterName string) string {
+	if providerName == "k3s" {
+		return "k3d-" + clusterName
+	}
+	if network := os.Getenv("KIND_EXPERIMENTAL_DOCKER_NETWORK"); network != "" {
+		return network
+	}
+	return "kind"
+}
+
+func buildCreateOptions(providerName string, profile config.Profile, ingressPort, workers int, mounts []providerpkg.Mount, mirrors []providerpkg.Mirror) (createOptions, error) {
 	switch providerName {
 	case "k3s":
 		return createOptions{NodeImage: profile.K3s.Image}, nil
+	case "kubeconfig":
+		return createOptions{}, nil
 	default:
-		configPath := profile.Kind.ConfigPath
-		if configPath == "" {
+		var base []byte
+		if profile.Kind.ConfigPath != "" {
 			var err error
-			configPath, err = writeKindConfig(ingressPort)
+			base, err = os.ReadFile(profile.Kind.ConfigPath)
 			if err != nil {
-				return createOptions{}, err
+				return createOptions{}, fmt.Errorf("read kind config: %w", err)
+			}
+		}
+		configPath, err := writeKindConfig(base, ingressPort, workers, mounts, mirrors)
+		if err != nil {
+			if profile.Kind.ConfigPath != "" {
+				return createOptions{}, fmt.Errorf("%s: %w", profile.Kind.ConfigPath, err)
 			}
+			return createOptions{}, err
 		}
 		return createOptions{
 			NodeImage:  profile.Kind.NodeImage,
@@ -254,32 +575,89 @@ func buildCreateOptions(providerName string, profile config.Profile, ingressPort
 	}
 }
 
-func resolveStackVersion(requested string) (string, error) {
-	if requested == "" || requested == "latest" {
-		return latestStackVersion(), nil
+func resolveStack(requested string) (stack.Stack, error) {
+	return stacks.Get(requested)
+}
+
+// installedStack returns the stack recorded in the management state. ok is
+// false when none is recorded or this binary does not know the version.
+func installedStack(ctx context.Context, contextName, namespace string) (stack.Stack, bool) {
+	version := installedStackVersion(ctx, contextName, namespace)
+	if version == "" {
+		return stack.Stack{}, false
+	}
+	s, err := stacks.Get(version)
+	return s, err == nil
+}
+
+// requireStackFeature fails when stack s lacks feature, which what needs.
+func requireStackFeature(s stack.Stack, feature, what string) error {
+	if s.HasFeature(feature) {
+		return nil
+	}
+	return fmt.Errorf("stack %s does not support %s (feature %s); see kplane stack list --wide", s.Version, what, feature)
+}
+
+// checkInstallFeatures verifies that stack s supports the auth settings the
+// management plane is installed with.
+func checkInstallFeatures(s stack.Stack, auth stacklatest.Auth, authzMode string) error {
+	if authzMode == stacklatest.AuthorizationModeRBAC {
+		if err := requireStackFeature(s, stack.FeatureRBAC, "--authorization-mode=rbac"); err != nil {
+			return err
+		}
+	}
+	if auth.ManagedIssuer() {
+		return requireStackFeature(s, stack.FeatureManagedIssuer, "--auth-policy=managed")
 	}
-	return "", fmt.Errorf("unsupported stack version %q (use latest)", requested)
+	return nil
 }
 
-func latestStackVersion() string {
-	return "latest"
+// stackPinNotes describes profile images and CRD sources that override stack
+// s. Pins are kept as written; the notes say how to follow the stack instead.
+func stackPinNotes(profile config.Profile, s stack.Stack) []string {
+	var notes []string
+	for _, pin := range []struct{ key, pinned, stack string }{
+		{"images.apiserver", profile.Images.Apiserver, s.Images.Apiserver},
+		{"images.operator", profile.Images.Operator, s.Images.Operator},
+		{"images.etcd", profile.Images.Etcd, s.Images.Etcd},
+		{"crdSource", profile.CRDSource, s.CRDSource},
+	} {
+		if pin.pinned != "" && pin.pinned != pin.stack {
+			notes = append(notes, fmt.Sprintf("profile pins %s %s over stack %s's %s; remove it from the profile to follow the stack", pin.key, pin.pinned, s.Version, pin.stack))
+		}
+	}
+	return notes
 }
 
-func writeKindConfig(ingressPort int) (string, error) {
-	content := fmt.Sprintf(`kind: Cluster
-apiVersion: kind.x-k8s.io/v1alpha4
-nodes:
-  - role: control-plane
-    extraPortMappings:
-      - containerPort: 443
-        hostPort: %d
-        listenAddress: "127.0.0.1"
-`, ingressPort)
+// applyStackDefaults fills images and the CRD source left unset by flags and
+// the profile from the stack registry.
+func applyStackDefaults(s stack.Stack, apiserverImg, operatorImg, etcdImg, crdSource *string) {
+	if *apiserverImg == "" {
+		*apiserverImg = s.Images.Apiserver
+	}
+	if *operatorImg == "" {
+		*operatorImg = s.Images.Operator
+	}
+	if *etcdImg == "" {
+		*etcdImg = s.Images.Etcd
+	}
+	if *crdSource == "" {
+		*crdSource = s.CRDSource
+	}
+}
+
+// writeKindConfig merges kplane's settings into base, the user's kind config
+// if any, and writes the result to a temp file.
+func writeKindConfig(base []byte, ingressPort, workers int, mounts []providerpkg.Mount, mirrors []providerpkg.Mirror) (string, error) {
+	content, err := mergeKindConfig(base, ingressPort, workers, mounts, mirrors)
+	if err != nil {
+		return "", err
+	}
 	file, err := os.CreateTemp("", "kplane-kind-config-*.yaml")
 	if err != nil {
 		return "", fmt.Errorf("create kind config: %w", err)
 	}
-	if _, err := file.WriteString(content); err != nil {
+	if _, err := file.Write(content); err != nil {
 		_ = file.Close()
 		return "", fmt.Errorf("write kind config: %w", err)
 	}
@@ -304,7 +682,7 @@ func resolveIngressPort(requested int) (int, error) {
 	return findFreePort()
 }
 
-func applyIngressConfig(ctx context.Context, contextName, namespace string, port int) error {
+func applyIngressConfig(ctx context.Context, contextName, namespace string, port int, externalURL string) error {
 	if namespace == "" {
 		namespace = "kplane-system"
 	}
@@ -316,12 +694,66 @@ metadata:
 data:
   ingressPort: "%d"
 `, ingressConfigName, namespace, port)
+	if externalURL != "" {
+		manifest += fmt.Sprintf("  externalURL: %q\n", strings.TrimSuffix(externalURL, "/"))
+	}
 	return kubectl.Apply(ctx, kubectl.ApplyOptions{
 		Context: contextName,
 		Stdin:   []byte(manifest),
 	})
 }
 
+// recordStackVersion stores the installed stack version next to the ingress
+// port.
+func recordStackVersion(ctx context.Context, contextName, namespace, version string) error {
+	return recordManagementValue(ctx, contextName, namespace, "stackVersion", version)
+}
+
+// recordManagementValue sets one key of the management state ConfigMap, or
+// removes it when value is empty. It merge-patches so the fields written by
+// applyIngressConfig stay put.
+func recordManagementValue(ctx context.Context, contextName, namespace, key, value string) error {
+	if namespace == "" {
+		namespace = "kplane-system"
+	}
+	var data any = value
+	if value == "" {
+		data = nil
+	}
+	patch, err := json.Marshal(map[string]any{"data": map[string]any{key: data}})
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", key, err)
+	}
+	err = kubectl.MergePatch(ctx, contextName, "configmap", ingressConfigName, namespace, patch)
+	if err == nil || !apierrors.IsNotFound(err) || value == "" {
+		return err
+	}
+	c, err := kubectl.ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ingressConfigName, Namespace: namespace},
+		Data:       map[string]string{key: value},
+	}
+	if _, err := c.Typed().CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("create %s configmap: %w", ingressConfigName, err)
+	}
+	return nil
+}
+
+func installedStackVersion(ctx context.Context, contextName, namespace string) string {
+	return managementValue(ctx, contextName, namespace, "stackVersion")
+}
+
+func managementValue(ctx context.Context, contextName, namespace, key string) string {
+	value, err := kubectl.GetJSONPath(ctx, contextName, "configmap", ingressConfigName, namespace, "{.data."+key+"}")
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
 func ensurePortAvailable(port int) error {
 	addr := fmt.Sprintf("127.0.0.1:%d", port)
 	listener, err := net.Listen("tcp", addr)
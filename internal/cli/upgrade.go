@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+	"github.com/spf13/cobra"
+)
+
+func newUpgradeCommand() *cobra.Command {
+	var (
+		toVersion      string
+		namespace      string
+		managementCtx  string
+		apiserverImg   string
+		operatorImg    string
+		etcdImg        string
+		crdSource      string
+		installCRDs    bool
+		dryRun         bool
+		rolloutTimeout time.Duration
+		quiet          bool
+		noColor        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the management plane to a stack version",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := mustConfig()
+			profile, err := cfg.ActiveProfile()
+			if err != nil {
+				return err
+			}
+			if err := resolveCertsTarget(profile, &namespace, &managementCtx); err != nil {
+				return err
+			}
+			for _, setting := range []struct {
+				value    *string
+				fallback string
+			}{
+				{&apiserverImg, profile.Images.Apiserver},
+				{&operatorImg, profile.Images.Operator},
+				{&etcdImg, profile.Images.Etcd},
+				{&crdSource, profile.CRDSource},
+				{&toVersion, profile.StackVersion},
+			} {
+				if *setting.value == "" {
+					*setting.value = setting.fallback
+				}
+			}
+			target, err := resolveStack(toVersion)
+			if err != nil {
+				return err
+			}
+			applyStackDefaults(target, &apiserverImg, &operatorImg, &etcdImg, &crdSource)
+			externalHost, err := resolveExternalHost(profile.Provider, profile.Kubeconfig.ExternalURL)
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			ui := NewUI(out, profile.UI.Enabled && !quiet, profile.UI.Color && !noColor)
+			installed := installedStackVersion(ctx, managementCtx, namespace)
+			if installed == "" {
+				installed = "unknown"
+			}
+
+			switch target.Manifests {
+			case stacklatest.Name:
+				authzMode, err := stacklatest.NormalizeAuthorizationMode(profile.AuthorizationMode)
+				if err != nil {
+					return err
+				}
+				if err := checkInstallFeatures(target, stacklatest.Auth{Policy: profile.Auth.Policy}, authzMode); err != nil {
+					return err
+				}
+				for _, note := range stackPinNotes(profile, target) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", note)
+				}
+				for _, note := range devReloadNotes(reloadedImages(ctx, managementCtx, namespace), stacklatest.Images{Apiserver: apiserverImg, Operator: operatorImg}) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", note)
+				}
+				opts := stacklatest.UpgradeOptions{
+					InstallOptions: stacklatest.InstallOptions{
+						Context:   managementCtx,
+						Namespace: namespace,
+						Images: stacklatest.Images{
+							Apiserver: apiserverImg,
+							Operator:  operatorImg,
+							Etcd:      etcdImg,
+						},
+						EtcdStorage: stacklatest.EtcdStorage{
+							Mode:         profile.Etcd.Storage,
+							Size:         profile.Etcd.Size,
+							StorageClass: profile.Etcd.StorageClass,
+						},
+						HA:                  profile.HA,
+						CRDSource:           crdSource,
+						InstallCRDs:         installCRDs,
+						Auth:                stacklatest.Auth{Policy: profile.Auth.Policy, IssuerTemplate: profile.Auth.IssuerTemplate},
+						AuthorizationMode:   authzMode,
+						TLSSANs:             appendSAN(append([]string{}, profile.TLSSANs...), externalHost),
+						IngressLoadBalancer: profile.Provider == "kubeconfig",
+						OIDCLoopbackPort:    managementValue(ctx, managementCtx, namespace, oidcLoopbackKey),
+						Logf: func(format string, args ...any) {
+							msg := fmt.Sprintf(format, args...)
+							if ui.Enabled() {
+								ui.Infof("upgrade: %s", msg)
+							} else {
+								fmt.Fprintf(out, "upgrade: %s\n", msg)
+							}
+						},
+					},
+					ControlPlaneClient: func(ctx context.Context, name string) (*kubectl.Client, error) {
+						return controlPlaneClient(ctx, managementCtx, namespace, name)
+					},
+					RolloutTimeout: rolloutTimeout,
+				}
+
+				var plan *stacklatest.UpgradePlan
+				if err := ui.Step("upgrade: diffing installed stack", func() error {
+					var err error
+					plan, err = stacklatest.PlanUpgrade(ctx, opts)
+					return err
+				}); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "stack: %s -> %s\n", installed, target.Version)
+				if plan.Empty() {
+					fmt.Fprintln(out, "images and CRDs already match the target stack")
+				} else {
+					w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+					fmt.Fprintln(w, "KIND\tNAME\tCURRENT\tTARGET")
+					for _, change := range append(append([]stacklatest.Change{}, plan.Images...), plan.CRDs...) {
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Kind, change.Name, orDash(change.Current), change.Target)
+					}
+					if err := w.Flush(); err != nil {
+						return err
+					}
+				}
+				for _, migration := range stacklatest.PreUpgrade {
+					fmt.Fprintf(out, "pre-upgrade: %s\n", migration.Name)
+				}
+				for _, migration := range stacklatest.PostUpgrade {
+					fmt.Fprintf(out, "post-upgrade: %s\n", migration.Name)
+				}
+				if dryRun {
+					return nil
+				}
+
+				if err := ui.Step("upgrade: rolling management plane", func() error {
+					return stacklatest.Upgrade(ctx, opts)
+				}); err != nil {
+					return err
+				}
+				if err := recordStackVersion(ctx, managementCtx, namespace, target.Version); err != nil {
+					return err
+				}
+				if err := clearReloadedImages(ctx, managementCtx, namespace); err != nil {
+					return err
+				}
+				if ui.Enabled() {
+					ui.Successf("ready: management plane is on stack %s", target.Version)
+				} else {
+					fmt.Fprintf(out, "ready: management plane is on stack %s\n", target.Version)
+				}
+				return nil
+			default:
+				return fmt.Errorf("stack %s uses unsupported manifests %q", target.Version, target.Manifests)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&toVersion, "to", "", "Target stack version (default: profile stackVersion)")
+	cmd.Flags().StringVar(&apiserverImg, "apiserver-image", "", "Apiserver image")
+	cmd.Flags().StringVar(&operatorImg, "operator-image", "", "Controlplane-operator image")
+	cmd.Flags().StringVar(&etcdImg, "etcd-image", "", "Etcd image")
+	cmd.Flags().StringVar(&crdSource, "crd-source", "", "CRD source (kustomize URL or path)")
+	cmd.Flags().BoolVar(&installCRDs, "install-crds", true, "Diff and apply CRDs")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the plan without changing anything")
+	cmd.Flags().DurationVar(&rolloutTimeout, "rollout-timeout", 5*time.Minute, "How long each component may take to become healthy")
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Namespace for kplane system")
+	cmd.Flags().StringVar(&managementCtx, "management-context", "", "Kubeconfig context for management plane (<provider>-<cluster>)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Disable progress output")
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	return cmd
+}
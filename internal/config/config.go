@@ -21,23 +21,43 @@ type Config struct {
 }
 
 type Profile struct {
-	Provider       string   `yaml:"provider"`
-	ClusterName    string   `yaml:"clusterName"`
-	Namespace      string   `yaml:"namespace"`
-	KubeconfigPath string   `yaml:"kubeconfigPath"`
-	StackVersion   string   `yaml:"stackVersion"`
-	CRDSource      string   `yaml:"crdSource"`
-	Images         Images   `yaml:"images"`
-	Auth           Auth     `yaml:"auth"`
-	Kind           KindOpts `yaml:"kind"`
-	K3s            K3sOpts  `yaml:"k3s"`
-	UI             UIOpts   `yaml:"ui"`
+	Provider          string         `yaml:"provider"`
+	ClusterName       string         `yaml:"clusterName"`
+	Namespace         string         `yaml:"namespace"`
+	KubeconfigPath    string         `yaml:"kubeconfigPath"`
+	StackVersion      string         `yaml:"stackVersion"`
+	CRDSource         string         `yaml:"crdSource,omitempty"`
+	TLSSANs           []string       `yaml:"tlsSANs,omitempty"`
+	AuthorizationMode string         `yaml:"authorizationMode,omitempty"`
+	Images            Images         `yaml:"images"`
+	Etcd              EtcdOpts       `yaml:"etcd,omitempty"`
+	HA                bool           `yaml:"ha,omitempty"`
+	Registry          RegistryOpts   `yaml:"registry,omitempty"`
+	Auth              Auth           `yaml:"auth"`
+	Kind              KindOpts       `yaml:"kind"`
+	K3s               K3sOpts        `yaml:"k3s"`
+	Kubeconfig        KubeconfigOpts `yaml:"kubeconfig"`
+	UI                UIOpts         `yaml:"ui"`
 }
 
 type Images struct {
-	Apiserver string `yaml:"apiserver"`
-	Operator  string `yaml:"operator"`
-	Etcd      string `yaml:"etcd"`
+	Apiserver string `yaml:"apiserver,omitempty"`
+	Operator  string `yaml:"operator,omitempty"`
+	Etcd      string `yaml:"etcd,omitempty"`
+}
+
+type EtcdOpts struct {
+	Storage      string `yaml:"storage,omitempty"`
+	Size         string `yaml:"size,omitempty"`
+	StorageClass string `yaml:"storageClass,omitempty"`
+	HostPath     string `yaml:"hostPath,omitempty"`
+}
+
+type RegistryOpts struct {
+	// Mirror runs pull-through caches for the stack's registries and points
+	// new kind and k3d clusters at them.
+	Mirror    bool     `yaml:"mirror,omitempty"`
+	Upstreams []string `yaml:"upstreams,omitempty"`
 }
 
 type Auth struct {
@@ -56,6 +76,14 @@ type K3sOpts struct {
 	IngressPort int    `yaml:"ingressPort"`
 }
 
+type KubeconfigOpts struct {
+	Path    string `yaml:"path"`
+	Context string `yaml:"context"`
+	// ExternalURL is where clients reach the ingress of a shared cluster,
+	// e.g. https://kplane.example.com.
+	ExternalURL string `yaml:"externalURL,omitempty"`
+}
+
 type UIOpts struct {
 	Enabled         bool `yaml:"enabled"`
 	Color           bool `yaml:"color"`
@@ -75,12 +103,6 @@ func Default() Config {
 				Namespace:      "kplane-system",
 				KubeconfigPath: filepath.Join(userHomeDir(), ".kube", "config"),
 				StackVersion:   "latest",
-				CRDSource:      "https://github.com/kplane-dev/controlplane-operator//config/crd?ref=main",
-				Images: Images{
-					Apiserver: "docker.io/kplanedev/apiserver:v0.0.10",
-					Operator:  "docker.io/kplanedev/controlplane-operator:v0.0.15",
-					Etcd:      "quay.io/coreos/etcd:v3.5.13",
-				},
 				Auth: Auth{
 					Policy:         "managed",
 					IssuerTemplate: "https://{externalHost}",
@@ -168,46 +190,3 @@ func userHomeDir() string {
 	}
 	return os.Getenv("HOME")
 }
-
-func ApplyDefaultImageUpgrades(cfg Config) (Config, bool) {
-	defaultProfile := Default().Profiles["default"]
-	changed := false
-	for name, profile := range cfg.Profiles {
-		updated := profile
-		updated.Images.Apiserver = upgradeApiserverImage(profile.Images.Apiserver, defaultProfile.Images.Apiserver)
-		if updated.Images.Apiserver != profile.Images.Apiserver {
-			changed = true
-		}
-		cfg.Profiles[name] = updated
-	}
-	return cfg, changed
-}
-
-func upgradeApiserverImage(current, latest string) string {
-	if current == "" {
-		return latest
-	}
-	if isDefaultApiserverImage(current) {
-		return latest
-	}
-	return current
-}
-
-func isDefaultApiserverImage(image string) bool {
-	switch image {
-	case
-		"docker.io/kplanedev/apiserver:v0.0.1",
-		"docker.io/kplanedev/apiserver:v0.0.2",
-		"docker.io/kplanedev/apiserver:v0.0.3",
-		"docker.io/kplanedev/apiserver:v0.0.4",
-		"docker.io/kplanedev/apiserver:v0.0.5",
-		"docker.io/kplanedev/apiserver:v0.0.6",
-		"docker.io/kplanedev/apiserver:v0.0.7",
-		"docker.io/kplanedev/apiserver:v0.0.8",
-		"docker.io/kplanedev/apiserver:v0.0.9",
-		"docker.io/kplanedev/apiserver:v0.0.10":
-		return true
-	default:
-		return false
-	}
-}
@@ -55,6 +55,25 @@ func RewriteServer(kubeconfigData []byte, server string) ([]byte, error) {
 	return out, nil
 }
 
+// SetCertificateAuthority pins every cluster to the given CA bundle and turns
+// off insecure-skip-tls-verify.
+func SetCertificateAuthority(kubeconfigData, caPEM []byte) ([]byte, error) {
+	cfg, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig: %w", err)
+	}
+	for _, cluster := range cfg.Clusters {
+		cluster.CertificateAuthority = ""
+		cluster.CertificateAuthorityData = caPEM
+		cluster.InsecureSkipTLSVerify = false
+	}
+	out, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("serialize kubeconfig: %w", err)
+	}
+	return out, nil
+}
+
 func RenameContext(kubeconfigData []byte, name string) ([]byte, error) {
 	cfg, err := clientcmd.Load(kubeconfigData)
 	if err != nil {
@@ -127,6 +146,40 @@ func SetCurrentContext(path, name string) error {
 	return nil
 }
 
+func RemoveContext(path, name string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("read kubeconfig: %w", err)
+	}
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		return false, fmt.Errorf("parse kubeconfig: %w", err)
+	}
+	_, hasContext := cfg.Contexts[name]
+	_, hasCluster := cfg.Clusters[name]
+	_, hasUser := cfg.AuthInfos[name]
+	if !hasContext && !hasCluster && !hasUser {
+		return false, nil
+	}
+	delete(cfg.Contexts, name)
+	delete(cfg.Clusters, name)
+	delete(cfg.AuthInfos, name)
+	if cfg.CurrentContext == name {
+		cfg.CurrentContext = ""
+	}
+	out, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return false, fmt.Errorf("serialize kubeconfig: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return false, fmt.Errorf("write kubeconfig: %w", err)
+	}
+	return true, nil
+}
+
 func ListContexts(path string) ([]string, string, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -165,3 +218,42 @@ func mergeConfigs(base, incoming *clientcmdapi.Config, setCurrent bool) *clientc
 	}
 	return base
 }
+
+// NewImpersonatingTokenConfig builds a single-context kubeconfig that
+// authenticates with a bearer token and acts as user and groups.
+func NewImpersonatingTokenConfig(name, server string, caPEM []byte, token, user string, groups []string) ([]byte, error) {
+	auth := clientcmdapi.NewAuthInfo()
+	auth.Token = token
+	auth.Impersonate = user
+	auth.ImpersonateGroups = groups
+	return newSingleContextConfig(name, server, caPEM, auth)
+}
+
+// NewExecConfig builds a single-context kubeconfig whose user runs an exec
+// credential plugin.
+func NewExecConfig(name, server string, caPEM []byte, exec *clientcmdapi.ExecConfig) ([]byte, error) {
+	user := clientcmdapi.NewAuthInfo()
+	user.Exec = exec
+	return newSingleContextConfig(name, server, caPEM, user)
+}
+
+func newSingleContextConfig(name, server string, caPEM []byte, user *clientcmdapi.AuthInfo) ([]byte, error) {
+	cfg := clientcmdapi.NewConfig()
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = server
+	cluster.CertificateAuthorityData = caPEM
+	cfg.Clusters[name] = cluster
+	cfg.AuthInfos[name] = user
+
+	context := clientcmdapi.NewContext()
+	context.Cluster = name
+	context.AuthInfo = name
+	cfg.Contexts[name] = context
+	cfg.CurrentContext = name
+
+	out, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("serialize kubeconfig: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,191 @@
+package kubectl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const fieldManager = "kplane"
+
+type Client struct {
+	config    *rest.Config
+	namespace string
+	dynamic   dynamic.Interface
+	typed     kubernetes.Interface
+	mapper    *restmapper.DeferredDiscoveryRESTMapper
+}
+
+var (
+	clientsMu      sync.Mutex
+	clients        = map[string]*Client{}
+	kubeconfigPath string
+)
+
+// SetKubeconfigPath makes ForContext read contexts from path instead of the
+// default kubeconfig. An empty path restores the default.
+func SetKubeconfigPath(path string) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if path != kubeconfigPath {
+		kubeconfigPath = path
+		clients = map[string]*Client{}
+	}
+}
+
+// ForContext returns a client for a context in the kubeconfig set with
+// SetKubeconfigPath, or the default one (KUBECONFIG or ~/.kube/config).
+// Clients are cached per context.
+func ForContext(contextName string) (*Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if c, ok := clients[contextName]; ok {
+		return c, nil
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfigPath
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	c, err := newClientFromConfig(clientConfig)
+	if err != nil {
+		if contextName == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("context %q: %w", contextName, err)
+	}
+	clients[contextName] = c
+	return c, nil
+}
+
+// ForKubeconfig returns an uncached client for the current context of the
+// given kubeconfig.
+func ForKubeconfig(kubeconfigData []byte) (*Client, error) {
+	clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig: %w", err)
+	}
+	return newClientFromConfig(clientConfig)
+}
+
+// Forget drops the cached client for a context, e.g. after its kubeconfig
+// entry was rewritten.
+func Forget(contextName string) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	delete(clients, contextName)
+}
+
+func newClientFromConfig(clientConfig clientcmd.ClientConfig) (*Client, error) {
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil || namespace == "" {
+		namespace = "default"
+	}
+	restConfig = rest.CopyConfig(restConfig)
+	restConfig.QPS = 50
+	restConfig.Burst = 100
+	restConfig.UserAgent = "kplane"
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create dynamic client: %w", err)
+	}
+	typedClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(typedClient.Discovery()))
+	return &Client{
+		config:    restConfig,
+		namespace: namespace,
+		dynamic:   dynamicClient,
+		typed:     typedClient,
+		mapper:    mapper,
+	}, nil
+}
+
+func (c *Client) RESTConfig() *rest.Config {
+	return rest.CopyConfig(c.config)
+}
+
+func (c *Client) Typed() kubernetes.Interface {
+	return c.typed
+}
+
+func (c *Client) Dynamic() dynamic.Interface {
+	return c.dynamic
+}
+
+// resolveResource maps kubectl-style resource arguments ("controlplane",
+// "deployments.apps", "configmaps") to a resource and its scope.
+func (c *Client) resolveResource(resource string) (*meta.RESTMapping, error) {
+	var gvr schema.GroupVersionResource
+	if fullySpecified, groupResource := schema.ParseResourceArg(resource); fullySpecified != nil {
+		gvr = *fullySpecified
+	} else {
+		gvr = groupResource.WithVersion("")
+	}
+	var mapping *meta.RESTMapping
+	err := c.retryNoMatch(0, func() error {
+		gvk, err := c.mapper.KindFor(gvr)
+		if err != nil {
+			return err
+		}
+		mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resolve resource %q: %w", resource, err)
+	}
+	return mapping, nil
+}
+
+func (c *Client) mappingFor(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	var mapping *meta.RESTMapping
+	err := c.retryNoMatch(30*time.Second, func() error {
+		var err error
+		mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resolve kind %s: %w", gvk.String(), err)
+	}
+	return mapping, nil
+}
+
+func (c *Client) resourceFor(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.dynamic.Resource(mapping.Resource)
+	}
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	return c.dynamic.Resource(mapping.Resource).Namespace(namespace)
+}
+
+// retryNoMatch refreshes discovery when a kind is unknown. Freshly applied
+// CRDs take a moment to be served, so applies keep retrying until wait expires.
+func (c *Client) retryNoMatch(wait time.Duration, fn func() error) error {
+	deadline := time.Now().Add(wait)
+	err := fn()
+	for err != nil && meta.IsNoMatchError(err) {
+		c.mapper.Reset()
+		if err = fn(); err == nil || !meta.IsNoMatchError(err) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+func Delete(ctx context.Context, contextName, resource, name, namespace string) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	return c.Delete(ctx, resource, name, namespace)
+}
+
+func WaitForDeletion(ctx context.Context, contextName, resource, name, namespace string, timeout time.Duration) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	return c.WaitForDeletion(ctx, resource, name, namespace, timeout)
+}
+
+// Delete removes an object. Objects that are already gone are not an error.
+func (c *Client) Delete(ctx context.Context, resource, name, namespace string) error {
+	mapping, err := c.resolveResource(resource)
+	if err != nil {
+		return err
+	}
+	if err := c.resourceFor(mapping, namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("delete %s %s: %w", resource, name, err)
+	}
+	return nil
+}
+
+// WaitForDeletion watches an object until it is gone, which includes waiting
+// for its finalizers to complete.
+func (c *Client) WaitForDeletion(ctx context.Context, resource, name, namespace string, timeout time.Duration) error {
+	mapping, err := c.resolveResource(resource)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ri := c.resourceFor(mapping, namespace)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return ri.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return ri.Watch(ctx, options)
+		},
+	}
+	gone := func(store cache.Store) (bool, error) {
+		return len(store.List()) == 0, nil
+	}
+	_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, gone, func(event watch.Event) (bool, error) {
+		return event.Type == watch.Deleted, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for %s %s deletion: %w", resource, name, err)
+	}
+	return nil
+}
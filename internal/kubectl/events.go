@@ -0,0 +1,50 @@
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+func ListEvents(ctx context.Context, contextName, kind, name string) ([]corev1.Event, error) {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListEvents(ctx, kind, name)
+}
+
+// ListEvents returns events for an object across all namespaces, oldest first.
+// Events for cluster-scoped objects are recorded in the default namespace.
+func (c *Client) ListEvents(ctx context.Context, kind, name string) ([]corev1.Event, error) {
+	selector := fields.Set{
+		"involvedObject.kind": kind,
+		"involvedObject.name": name,
+	}.AsSelector().String()
+	list, err := c.typed.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("list events for %s %s: %w", kind, name, err)
+	}
+	events := list.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return EventTime(events[i]).Time.Before(EventTime(events[j]).Time)
+	})
+	return events, nil
+}
+
+func EventTime(event corev1.Event) metav1.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp
+	case !event.EventTime.IsZero():
+		return metav1.NewTime(event.EventTime.Time)
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp
+	default:
+		return event.CreationTimestamp
+	}
+}
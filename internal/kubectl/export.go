@@ -0,0 +1,279 @@
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// skippedResources are served but either derived from other objects or owned
+// by the apiserver and its controllers, so they are never exported.
+var skippedResources = map[schema.GroupResource]bool{
+	{Resource: "events"}:                                                   true,
+	{Group: "events.k8s.io", Resource: "events"}:                           true,
+	{Resource: "nodes"}:                                                    true,
+	{Resource: "componentstatuses"}:                                        true,
+	{Group: "discovery.k8s.io", Resource: "endpointslices"}:                true,
+	{Group: "coordination.k8s.io", Resource: "leases"}:                     true,
+	{Group: "certificates.k8s.io", Resource: "certificatesigningrequests"}: true,
+}
+
+var systemNamespaces = map[string]bool{
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// ExportedResource is one served resource and the objects exported from it.
+type ExportedResource struct {
+	Resource   schema.GroupVersionResource
+	Namespaced bool
+	Objects    []*unstructured.Unstructured
+}
+
+// ExportObjects lists every listable and creatable resource the server
+// prefers, drops system and controller-owned objects and strips server-managed
+// fields from the rest. exclude holds resource or kind names (e.g.
+// "secrets", "Secret", "deployments.apps") to leave out.
+func (c *Client) ExportObjects(ctx context.Context, exclude []string) ([]ExportedResource, error) {
+	lists, err := c.typed.Discovery().ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("discover resources: %w", err)
+	}
+	excluded := newExcludeSet(exclude)
+	var exported []ExportedResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			gr := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
+			if strings.Contains(resource.Name, "/") || skippedResources[gr] ||
+				!hasVerbs(resource.Verbs, "list", "create") || excluded.matches(gr, resource.Kind) {
+				continue
+			}
+			gvr := gv.WithResource(resource.Name)
+			items, err := c.dynamic.Resource(gvr).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) || apierrors.IsForbidden(err) {
+					continue
+				}
+				return nil, fmt.Errorf("list %s: %w", gr.String(), err)
+			}
+			entry := ExportedResource{Resource: gvr, Namespaced: resource.Namespaced}
+			for i := range items.Items {
+				obj := &items.Items[i]
+				if obj.GetAPIVersion() == "" {
+					obj.SetAPIVersion(gv.String())
+				}
+				if obj.GetKind() == "" {
+					obj.SetKind(resource.Kind)
+				}
+				if isSystemObject(obj) {
+					continue
+				}
+				CleanObject(obj)
+				entry.Objects = append(entry.Objects, obj)
+			}
+			if len(entry.Objects) > 0 {
+				exported = append(exported, entry)
+			}
+		}
+	}
+	return exported, nil
+}
+
+// excludeSet holds the lower-cased names passed to --exclude. A name matches
+// a resource by its plural ("secrets"), its group-qualified plural
+// ("deployments.apps") or its kind ("Deployment").
+type excludeSet map[string]bool
+
+func newExcludeSet(names []string) excludeSet {
+	set := excludeSet{}
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+func (s excludeSet) matches(gr schema.GroupResource, kind string) bool {
+	return s[gr.Resource] || s[gr.String()] || s[strings.ToLower(kind)]
+}
+
+func hasVerbs(verbs metav1.Verbs, required ...string) bool {
+	for _, verb := range required {
+		found := false
+		for _, v := range verbs {
+			if v == verb {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// isSystemObject reports objects the apiserver or a controller recreates on
+// its own: anything with an owner, bootstrap RBAC and APF defaults, the
+// per-namespace CA bundle and default service account, and the contents of
+// the kube-* namespaces.
+func isSystemObject(obj *unstructured.Unstructured) bool {
+	if len(obj.GetOwnerReferences()) > 0 {
+		return true
+	}
+	name, namespace := obj.GetName(), obj.GetNamespace()
+	if systemNamespaces[namespace] {
+		return true
+	}
+	labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+	if labels["kubernetes.io/bootstrapping"] == "rbac-defaults" ||
+		labels["kube-aggregator.kubernetes.io/automanaged"] != "" ||
+		annotations["apf.kubernetes.io/autoupdate-spec"] == "true" ||
+		annotations["endpoints.kubernetes.io/last-change-trigger-time"] != "" {
+		return true
+	}
+	switch obj.GetKind() {
+	case "Namespace":
+		return name == "default" || systemNamespaces[name]
+	case "ConfigMap":
+		return name == "kube-root-ca.crt"
+	case "ServiceAccount":
+		return name == "default"
+	case "Secret":
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		return secretType == "kubernetes.io/service-account-token"
+	case "Service", "Endpoints":
+		return namespace == "default" && name == "kubernetes"
+	case "ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding":
+		return strings.HasPrefix(name, "system:")
+	case "PriorityClass":
+		return strings.HasPrefix(name, "system-")
+	}
+	return false
+}
+
+// CleanObject removes fields the server sets, so the object can be applied to
+// another cluster.
+func CleanObject(obj *unstructured.Unstructured) {
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds", "managedFields", "selfLink", "ownerReferences"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	annotations := obj.GetAnnotations()
+	for key := range annotations {
+		if key == "kubectl.kubernetes.io/last-applied-configuration" || key == "deployment.kubernetes.io/revision" ||
+			strings.HasPrefix(key, "pv.kubernetes.io/") || strings.HasPrefix(key, "volume.beta.kubernetes.io/") || strings.HasPrefix(key, "volume.kubernetes.io/") {
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+
+	switch obj.GetKind() {
+	case "Service":
+		if clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); clusterIP != "None" {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+		unstructured.RemoveNestedField(obj.Object, "spec", "healthCheckNodePort")
+	case "PersistentVolumeClaim":
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+	case "Pod":
+		unstructured.RemoveNestedField(obj.Object, "spec", "nodeName")
+	case "ServiceAccount":
+		unstructured.RemoveNestedField(obj.Object, "secrets")
+	case "Job":
+		unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+		for _, path := range [][]string{{"metadata", "labels"}, {"spec", "template", "metadata", "labels"}} {
+			labels, found, _ := unstructured.NestedStringMap(obj.Object, path...)
+			if !found {
+				continue
+			}
+			for _, key := range []string{"controller-uid", "batch.kubernetes.io/controller-uid"} {
+				delete(labels, key)
+			}
+			_ = unstructured.SetNestedStringMap(obj.Object, labels, path...)
+		}
+	}
+}
+
+// SortForImport orders objects so that CRDs are applied first, then
+// namespaces, then everything else, keeping the input order within a group.
+func SortForImport(objects []*unstructured.Unstructured) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return importRank(objects[i]) < importRank(objects[j])
+	})
+}
+
+func importRank(obj *unstructured.Unstructured) int {
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:
+		return 0
+	case schema.GroupKind{Kind: "Namespace"}:
+		return 1
+	}
+	return 2
+}
+
+// ApplyAll applies objects in import order with up to parallel concurrent
+// requests. Each rank (CRDs, namespaces, the rest) finishes before the next
+// starts. done is called once per object, from any goroutine. It returns the
+// number of objects that failed.
+func (c *Client) ApplyAll(ctx context.Context, objects []*unstructured.Unstructured, parallel int, done func(obj *unstructured.Unstructured, err error)) int {
+	if parallel < 1 {
+		parallel = 1
+	}
+	SortForImport(objects)
+	var (
+		mu     sync.Mutex
+		failed int
+	)
+	for start := 0; start < len(objects); {
+		end := start
+		for end < len(objects) && importRank(objects[end]) == importRank(objects[start]) {
+			end++
+		}
+		work := make(chan *unstructured.Unstructured)
+		var wg sync.WaitGroup
+		for i := 0; i < parallel && i < end-start; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for obj := range work {
+					err := c.ApplyObject(ctx, obj)
+					if err != nil {
+						mu.Lock()
+						failed++
+						mu.Unlock()
+					}
+					if done != nil {
+						done(obj, err)
+					}
+				}
+			}()
+		}
+		for _, obj := range objects[start:end] {
+			work <- obj
+		}
+		close(work)
+		wg.Wait()
+		start = end
+	}
+	return failed
+}
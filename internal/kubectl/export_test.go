@@ -0,0 +1,204 @@
+package kubectl
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func object(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestSortForImport(t *testing.T) {
+	objects := []*unstructured.Unstructured{
+		object("v1", "ConfigMap", "app", "settings"),
+		object("v1", "Namespace", "", "app"),
+		object("example.com/v1", "Widget", "app", "w"),
+		object("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com"),
+		object("v1", "Secret", "app", "token"),
+		object("v1", "Namespace", "", "other"),
+		object("example.com/v1", "CustomResourceDefinition", "", "lookalike"),
+	}
+	SortForImport(objects)
+	var got []string
+	for _, obj := range objects {
+		got = append(got, obj.GetKind()+"/"+obj.GetName())
+	}
+	want := []string{
+		"CustomResourceDefinition/widgets.example.com",
+		"Namespace/app",
+		"Namespace/other",
+		"ConfigMap/settings",
+		"Widget/w",
+		"Secret/token",
+		"CustomResourceDefinition/lookalike",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestCleanObject(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]any
+		want map[string]any
+	}{
+		{
+			name: "server metadata and status",
+			in: map[string]any{
+				"apiVersion": "v1", "kind": "ConfigMap",
+				"metadata": map[string]any{
+					"name": "settings", "namespace": "app",
+					"uid": "1", "resourceVersion": "2", "generation": int64(3), "creationTimestamp": "now",
+					"managedFields":   []any{map[string]any{"manager": "kubectl"}},
+					"ownerReferences": []any{map[string]any{"name": "owner"}},
+					"labels":          map[string]any{"app": "demo"},
+					"annotations": map[string]any{
+						"kubectl.kubernetes.io/last-applied-configuration": "{}",
+						"team": "platform",
+					},
+				},
+				"data":   map[string]any{"key": "value"},
+				"status": map[string]any{"phase": "Active"},
+			},
+			want: map[string]any{
+				"apiVersion": "v1", "kind": "ConfigMap",
+				"metadata": map[string]any{
+					"name": "settings", "namespace": "app",
+					"labels":      map[string]any{"app": "demo"},
+					"annotations": map[string]any{"team": "platform"},
+				},
+				"data": map[string]any{"key": "value"},
+			},
+		},
+		{
+			name: "only server annotations",
+			in: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"name": "web", "annotations": map[string]any{"deployment.kubernetes.io/revision": "4"}},
+			},
+			want: map[string]any{
+				"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": map[string]any{"name": "web"},
+			},
+		},
+		{
+			name: "service cluster IP",
+			in: map[string]any{
+				"apiVersion": "v1", "kind": "Service",
+				"metadata": map[string]any{"name": "web"},
+				"spec":     map[string]any{"clusterIP": "10.96.0.10", "clusterIPs": []any{"10.96.0.10"}, "healthCheckNodePort": int64(30000), "type": "LoadBalancer"},
+			},
+			want: map[string]any{
+				"apiVersion": "v1", "kind": "Service",
+				"metadata": map[string]any{"name": "web"},
+				"spec":     map[string]any{"type": "LoadBalancer"},
+			},
+		},
+		{
+			name: "headless service",
+			in: map[string]any{
+				"apiVersion": "v1", "kind": "Service",
+				"metadata": map[string]any{"name": "db"},
+				"spec":     map[string]any{"clusterIP": "None", "clusterIPs": []any{"None"}},
+			},
+			want: map[string]any{
+				"apiVersion": "v1", "kind": "Service",
+				"metadata": map[string]any{"name": "db"},
+				"spec":     map[string]any{"clusterIP": "None", "clusterIPs": []any{"None"}},
+			},
+		},
+		{
+			name: "bound claim",
+			in: map[string]any{
+				"apiVersion": "v1", "kind": "PersistentVolumeClaim",
+				"metadata": map[string]any{"name": "data", "annotations": map[string]any{"pv.kubernetes.io/bind-completed": "yes"}},
+				"spec":     map[string]any{"volumeName": "pvc-123", "storageClassName": "standard"},
+			},
+			want: map[string]any{
+				"apiVersion": "v1", "kind": "PersistentVolumeClaim",
+				"metadata": map[string]any{"name": "data"},
+				"spec":     map[string]any{"storageClassName": "standard"},
+			},
+		},
+		{
+			name: "job selector and controller labels",
+			in: map[string]any{
+				"apiVersion": "batch/v1", "kind": "Job",
+				"metadata": map[string]any{"name": "migrate", "labels": map[string]any{"controller-uid": "1", "app": "db"}},
+				"spec": map[string]any{
+					"selector": map[string]any{"matchLabels": map[string]any{"controller-uid": "1"}},
+					"template": map[string]any{"metadata": map[string]any{"labels": map[string]any{"batch.kubernetes.io/controller-uid": "1", "app": "db"}}},
+				},
+			},
+			want: map[string]any{
+				"apiVersion": "batch/v1", "kind": "Job",
+				"metadata": map[string]any{"name": "migrate", "labels": map[string]any{"app": "db"}},
+				"spec": map[string]any{
+					"template": map[string]any{"metadata": map[string]any{"labels": map[string]any{"app": "db"}}},
+				},
+			},
+		},
+		{
+			name: "service account secrets",
+			in: map[string]any{
+				"apiVersion": "v1", "kind": "ServiceAccount",
+				"metadata": map[string]any{"name": "builder"},
+				"secrets":  []any{map[string]any{"name": "builder-token"}},
+			},
+			want: map[string]any{
+				"apiVersion": "v1", "kind": "ServiceAccount",
+				"metadata": map[string]any{"name": "builder"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: tt.in}
+			CleanObject(obj)
+			if !reflect.DeepEqual(obj.Object, tt.want) {
+				t.Errorf("CleanObject() = %v, want %v", obj.Object, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeSet(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	secrets := schema.GroupResource{Resource: "secrets"}
+	tests := []struct {
+		name    string
+		exclude []string
+		gr      schema.GroupResource
+		kind    string
+		want    bool
+	}{
+		{name: "empty", gr: secrets, kind: "Secret"},
+		{name: "plural", exclude: []string{"secrets"}, gr: secrets, kind: "Secret", want: true},
+		{name: "kind", exclude: []string{"Secret"}, gr: secrets, kind: "Secret", want: true},
+		{name: "kind lower", exclude: []string{"deployment"}, gr: deployments, kind: "Deployment", want: true},
+		{name: "group qualified", exclude: []string{"deployments.apps"}, gr: deployments, kind: "Deployment", want: true},
+		{name: "plural in group", exclude: []string{"deployments"}, gr: deployments, kind: "Deployment", want: true},
+		{name: "other group", exclude: []string{"deployments.extensions"}, gr: deployments, kind: "Deployment"},
+		{name: "core qualified", exclude: []string{"secrets.v1"}, gr: secrets, kind: "Secret"},
+		{name: "mixed case and spaces", exclude: []string{" Deployments.Apps "}, gr: deployments, kind: "Deployment", want: true},
+		{name: "blank entry", exclude: []string{""}, gr: secrets, kind: "Secret"},
+		{name: "unrelated", exclude: []string{"configmaps", "Service"}, gr: secrets, kind: "Secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newExcludeSet(tt.exclude).matches(tt.gr, tt.kind); got != tt.want {
+				t.Errorf("newExcludeSet(%q).matches(%s, %s) = %v, want %v", tt.exclude, tt.gr, tt.kind, got, tt.want)
+			}
+		})
+	}
+}
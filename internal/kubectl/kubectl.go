@@ -3,21 +3,25 @@ package kubectl
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"io"
+	"net/http"
+	"os"
 	"strings"
 	"time"
-)
-
-const binaryName = "kubectl"
 
-func EnsureInstalled() error {
-	if _, err := exec.LookPath(binaryName); err != nil {
-		return fmt.Errorf("kubectl is not installed; install from https://kubernetes.io/docs/tasks/tools/")
-	}
-	return nil
-}
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
 
 type ApplyOptions struct {
 	Context string
@@ -26,145 +30,344 @@ type ApplyOptions struct {
 }
 
 func Apply(ctx context.Context, opts ApplyOptions) error {
-	args := []string{"apply"}
-	if opts.Context != "" {
-		args = append(args, "--context", opts.Context)
+	c, err := ForContext(opts.Context)
+	if err != nil {
+		return err
 	}
+	manifest := opts.Stdin
 	if opts.Path != "" {
-		args = append(args, "-f", opts.Path)
-	} else {
-		args = append(args, "-f", "-")
-	}
-	cmd := exec.CommandContext(ctx, binaryName, args...)
-	if opts.Stdin != nil {
-		cmd.Stdin = bytes.NewReader(opts.Stdin)
-	}
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("kubectl apply: %s", strings.TrimSpace(stderr.String()))
+		manifest, err = ReadManifest(ctx, opts.Path)
+		if err != nil {
+			return err
+		}
 	}
-	return nil
+	return c.Apply(ctx, manifest)
 }
 
 func ApplyKustomize(ctx context.Context, contextName, path string) error {
-	args := []string{"apply"}
-	if contextName != "" {
-		args = append(args, "--context", contextName)
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
 	}
-	args = append(args, "-k", path)
-	cmd := exec.CommandContext(ctx, binaryName, args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("kubectl apply -k: %s", strings.TrimSpace(stderr.String()))
+	manifest, err := BuildKustomize(path)
+	if err != nil {
+		return err
 	}
-	return nil
+	return c.Apply(ctx, manifest)
 }
 
 func ApplyURL(ctx context.Context, contextName, url string) error {
-	args := []string{"apply"}
-	if contextName != "" {
-		args = append(args, "--context", contextName)
+	return Apply(ctx, ApplyOptions{Context: contextName, Path: url})
+}
+
+func CreateNamespace(ctx context.Context, contextName, name string) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
 	}
-	args = append(args, "-f", url)
-	_, stderr, err := run(ctx, args...)
+	return c.CreateNamespace(ctx, name)
+}
+
+func LabelNodes(ctx context.Context, contextName, selector string, labels map[string]string) error {
+	c, err := ForContext(contextName)
 	if err != nil {
-		return fmt.Errorf("kubectl apply -f: %s", strings.TrimSpace(stderr))
+		return err
 	}
-	return nil
+	return c.LabelNodes(ctx, selector, labels)
 }
 
-func CreateNamespace(ctx context.Context, contextName, name string) error {
-	args := []string{"create", "namespace", name}
-	if contextName != "" {
-		args = append([]string{"--context", contextName}, args...)
-	}
-	cmd := exec.CommandContext(ctx, binaryName, args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		if strings.Contains(stderr.String(), "AlreadyExists") {
-			return nil
+func RolloutStatus(ctx context.Context, contextName, namespace, kind, name string, timeout time.Duration) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	return c.RolloutStatus(ctx, namespace, kind, name, timeout)
+}
+
+func GetJSONPath(ctx context.Context, contextName, resource, name, namespace, jsonPath string) (string, error) {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return "", err
+	}
+	return c.GetJSONPath(ctx, resource, name, namespace, jsonPath)
+}
+
+func Get(ctx context.Context, contextName, resource, name, namespace string) (*unstructured.Unstructured, error) {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return c.Get(ctx, resource, name, namespace)
+}
+
+func List(ctx context.Context, contextName, resource, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return c.List(ctx, resource, namespace, opts)
+}
+
+func GetSecretData(ctx context.Context, contextName, name, namespace, key string) ([]byte, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("namespace is required for secret %q", name)
+	}
+	c, err := ForContext(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetSecretData(ctx, name, namespace, key)
+}
+
+func MergePatch(ctx context.Context, contextName, resource, name, namespace string, patch []byte) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	return c.MergePatch(ctx, resource, name, namespace, patch)
+}
+
+func StrategicMergePatch(ctx context.Context, contextName, resource, name, namespace string, patch []byte) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	return c.StrategicMergePatch(ctx, resource, name, namespace, patch)
+}
+
+func BuildKustomize(path string) ([]byte, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := kustomizer.Run(filesys.MakeFsOnDisk(), path)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build %s: %w", path, err)
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build %s: %w", path, err)
+	}
+	return out, nil
+}
+
+func (c *Client) Apply(ctx context.Context, manifest []byte) error {
+	objects, err := DecodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := c.ApplyObject(ctx, obj); err != nil {
+			return err
 		}
-		return fmt.Errorf("create namespace: %s", strings.TrimSpace(stderr.String()))
 	}
 	return nil
 }
 
-func LabelNodes(ctx context.Context, contextName string, labels map[string]string) error {
-	args := []string{"label", "nodes", "--all"}
-	if contextName != "" {
-		args = append([]string{"--context", contextName}, args...)
+func (c *Client) ApplyObject(ctx context.Context, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mappingFor(gvk)
+	if err != nil {
+		return err
 	}
-	for key, value := range labels {
-		args = append(args, fmt.Sprintf("%s=%s", key, value))
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("encode %s %s: %w", strings.ToLower(gvk.Kind), obj.GetName(), err)
 	}
-	args = append(args, "--overwrite")
-	_, stderr, err := run(ctx, args...)
+	force := true
+	_, err = c.resourceFor(mapping, obj.GetNamespace()).Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	})
 	if err != nil {
-		return fmt.Errorf("kubectl label nodes: %s", strings.TrimSpace(stderr))
+		return fmt.Errorf("apply %s %s: %w", strings.ToLower(gvk.Kind), obj.GetName(), err)
 	}
 	return nil
 }
 
-func RolloutStatus(ctx context.Context, contextName, namespace, kind, name string, timeout time.Duration) error {
-	args := []string{"rollout", "status", fmt.Sprintf("%s/%s", kind, name), fmt.Sprintf("--timeout=%s", timeout.String())}
-	if namespace != "" {
-		args = append([]string{"-n", namespace}, args...)
+// MergePatch applies a JSON merge patch. Unlike Apply it leaves fields that
+// are not mentioned in the patch untouched regardless of who owns them.
+func (c *Client) MergePatch(ctx context.Context, resource, name, namespace string, patch []byte) error {
+	mapping, err := c.resolveResource(resource)
+	if err != nil {
+		return err
 	}
-	if contextName != "" {
-		args = append([]string{"--context", contextName}, args...)
+	if _, err := c.resourceFor(mapping, namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager}); err != nil {
+		return fmt.Errorf("patch %s %s: %w", resource, name, err)
 	}
-	_, stderr, err := run(ctx, args...)
+	return nil
+}
+
+// StrategicMergePatch patches a built-in type using its merge keys, e.g. to
+// add a container without replacing the whole list.
+func (c *Client) StrategicMergePatch(ctx context.Context, resource, name, namespace string, patch []byte) error {
+	mapping, err := c.resolveResource(resource)
 	if err != nil {
-		return fmt.Errorf("kubectl rollout status: %s", strings.TrimSpace(stderr))
+		return err
+	}
+	if _, err := c.resourceFor(mapping, namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager}); err != nil {
+		return fmt.Errorf("patch %s %s: %w", resource, name, err)
 	}
 	return nil
 }
 
-func GetJSONPath(ctx context.Context, contextName, resource, name, namespace, jsonPath string) (string, error) {
-	args := []string{"get", resource}
-	if name != "" {
-		args = append(args, name)
+func (c *Client) CreateNamespace(ctx context.Context, name string) error {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if _, err := c.typed.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{FieldManager: fieldManager}); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return fmt.Errorf("create namespace: %w", err)
+	}
+	return nil
+}
+
+// LabelNodes labels the nodes matching selector, or every node when the
+// selector is empty.
+func (c *Client) LabelNodes(ctx context.Context, selector string, labels map[string]string) error {
+	nodes, err := c.typed.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("label nodes: %w", err)
 	}
-	if namespace != "" {
-		args = append([]string{"-n", namespace}, args...)
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"labels": labels}})
+	if err != nil {
+		return fmt.Errorf("label nodes: %w", err)
 	}
-	if contextName != "" {
-		args = append([]string{"--context", contextName}, args...)
+	for _, node := range nodes.Items {
+		if _, err := c.typed.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager}); err != nil {
+			return fmt.Errorf("label node %s: %w", node.Name, err)
+		}
 	}
-	args = append(args, "-o", "jsonpath="+jsonPath)
-	stdout, stderr, err := run(ctx, args...)
+	return nil
+}
+
+func (c *Client) Get(ctx context.Context, resource, name, namespace string) (*unstructured.Unstructured, error) {
+	mapping, err := c.resolveResource(resource)
 	if err != nil {
-		return "", fmt.Errorf("kubectl get %s: %s", resource, strings.TrimSpace(stderr))
+		return nil, err
 	}
-	return strings.TrimSpace(stdout), nil
+	obj, err := c.resourceFor(mapping, namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get %s %s: %w", resource, name, err)
+	}
+	return obj, nil
 }
 
-func GetSecretData(ctx context.Context, contextName, name, namespace, key string) ([]byte, error) {
-	if namespace == "" {
-		return nil, fmt.Errorf("namespace is required for secret %q", name)
+func (c *Client) List(ctx context.Context, resource, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	mapping, err := c.resolveResource(resource)
+	if err != nil {
+		return nil, err
 	}
-	value, err := GetJSONPath(ctx, contextName, "secret", name, namespace, "{.data."+key+"}")
+	list, err := c.resourceFor(mapping, namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", resource, err)
+	}
+	return list, nil
+}
+
+func (c *Client) GetJSONPath(ctx context.Context, resource, name, namespace, jsonPath string) (string, error) {
+	var data any
+	if name != "" {
+		obj, err := c.Get(ctx, resource, name, namespace)
+		if err != nil {
+			return "", err
+		}
+		data = obj.UnstructuredContent()
+	} else {
+		list, err := c.List(ctx, resource, namespace, metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+		data = list.UnstructuredContent()
+	}
+	return evalJSONPath(data, jsonPath)
+}
+
+func (c *Client) GetSecretData(ctx context.Context, name, namespace, key string) ([]byte, error) {
+	data, err := c.GetSecret(ctx, name, namespace)
 	if err != nil {
 		return nil, err
 	}
-	if value == "" {
+	value, ok := data[key]
+	if !ok || len(value) == 0 {
 		return nil, fmt.Errorf("secret %q missing key %q", name, key)
 	}
-	out, err := base64.StdEncoding.DecodeString(value)
+	return value, nil
+}
+
+func (c *Client) GetSecret(ctx context.Context, name, namespace string) (map[string][]byte, error) {
+	secret, err := c.typed.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("decode secret %q key %q: %w", name, key, err)
+		return nil, fmt.Errorf("get secret %s: %w", name, err)
 	}
-	return out, nil
+	return secret.Data, nil
 }
 
-func run(ctx context.Context, args ...string) (string, string, error) {
-	cmd := exec.CommandContext(ctx, binaryName, args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	return stdout.String(), stderr.String(), err
+func DecodeManifest(manifest []byte) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		var raw map[string]any
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("decode manifest: %w", err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("decode manifest: %w", err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("decode manifest: object %q is missing apiVersion or kind", obj.GetName())
+		}
+		objects = append(objects, obj)
+	}
+}
+
+func evalJSONPath(data any, template string) (string, error) {
+	parser := jsonpath.New("kplane").AllowMissingKeys(true)
+	if err := parser.Parse(template); err != nil {
+		return "", fmt.Errorf("parse jsonpath %q: %w", template, err)
+	}
+	var out bytes.Buffer
+	if err := parser.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("evaluate jsonpath %q: %w", template, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// ReadManifest loads a manifest from a local path or an http(s) URL.
+func ReadManifest(ctx context.Context, path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read manifest: %w", err)
+		}
+		return data, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch manifest %s: %s", path, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	return data, nil
 }
@@ -0,0 +1,154 @@
+package kubectl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+var rolloutResources = map[string]schema.GroupVersionResource{
+	"deployment":  appsv1.SchemeGroupVersion.WithResource("deployments"),
+	"statefulset": appsv1.SchemeGroupVersion.WithResource("statefulsets"),
+	"daemonset":   appsv1.SchemeGroupVersion.WithResource("daemonsets"),
+}
+
+func (c *Client) RolloutStatus(ctx context.Context, namespace, kind, name string, timeout time.Duration) error {
+	kind = strings.TrimSuffix(strings.ToLower(kind), "s")
+	gvr, ok := rolloutResources[kind]
+	if !ok {
+		return fmt.Errorf("rollout status: unsupported kind %q", kind)
+	}
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resource := c.dynamic.Resource(gvr).Namespace(namespace)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return resource.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return resource.Watch(ctx, options)
+		},
+	}
+	_, err := watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("%s %s was deleted", kind, name)
+		}
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			return false, nil
+		}
+		return rolloutComplete(kind, obj)
+	})
+	if err != nil {
+		return fmt.Errorf("rollout status %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
+
+func RolloutRestart(ctx context.Context, contextName, namespace, kind, name string) error {
+	c, err := ForContext(contextName)
+	if err != nil {
+		return err
+	}
+	return c.RolloutRestart(ctx, namespace, kind, name)
+}
+
+// RolloutRestart bumps the pod template the same way `kubectl rollout restart`
+// does. Workloads that do not exist yet are skipped.
+func (c *Client) RolloutRestart(ctx context.Context, namespace, kind, name string) error {
+	kind = strings.TrimSuffix(strings.ToLower(kind), "s")
+	gvr, ok := rolloutResources[kind]
+	if !ok {
+		return fmt.Errorf("rollout restart: unsupported kind %q", kind)
+	}
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`, time.Now().Format(time.RFC3339))
+	_, err := c.dynamic.Resource(gvr).Namespace(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("rollout restart %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
+
+// rolloutComplete mirrors the checks behind `kubectl rollout status`.
+func rolloutComplete(kind string, obj *unstructured.Unstructured) (bool, error) {
+	switch kind {
+	case "deployment":
+		var deployment appsv1.Deployment
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &deployment); err != nil {
+			return false, err
+		}
+		if deployment.Generation > deployment.Status.ObservedGeneration {
+			return false, nil
+		}
+		for _, cond := range deployment.Status.Conditions {
+			if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+				return false, fmt.Errorf("deployment %q exceeded its progress deadline", deployment.Name)
+			}
+		}
+		if deployment.Spec.Replicas != nil && deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas {
+			return false, nil
+		}
+		if deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+			return false, nil
+		}
+		return deployment.Status.AvailableReplicas >= deployment.Status.UpdatedReplicas, nil
+	case "statefulset":
+		var sts appsv1.StatefulSet
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &sts); err != nil {
+			return false, err
+		}
+		if sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+			return true, nil
+		}
+		if sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration {
+			return false, nil
+		}
+		if sts.Spec.Replicas != nil && sts.Status.ReadyReplicas < *sts.Spec.Replicas {
+			return false, nil
+		}
+		if rolling := sts.Spec.UpdateStrategy.RollingUpdate; rolling != nil && rolling.Partition != nil && sts.Spec.Replicas != nil {
+			return sts.Status.UpdatedReplicas >= *sts.Spec.Replicas-*rolling.Partition, nil
+		}
+		return sts.Status.UpdateRevision == sts.Status.CurrentRevision, nil
+	case "daemonset":
+		var ds appsv1.DaemonSet
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &ds); err != nil {
+			return false, err
+		}
+		if ds.Generation > ds.Status.ObservedGeneration {
+			return false, nil
+		}
+		if ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled {
+			return false, nil
+		}
+		return ds.Status.NumberAvailable >= ds.Status.DesiredNumberScheduled, nil
+	default:
+		return false, fmt.Errorf("unsupported kind %q", kind)
+	}
+}
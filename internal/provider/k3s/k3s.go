@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
@@ -56,10 +58,23 @@ func ListClusters(ctx context.Context) ([]string, error) {
 	return clusters, nil
 }
 
+type Volume struct {
+	HostPath      string
+	ContainerPath string
+}
+
+type Mirror struct {
+	Upstream string
+	Endpoint string
+}
+
 type CreateOptions struct {
 	Name        string
 	Image       string
 	IngressPort int
+	Agents      int
+	Volumes     []Volume
+	Mirrors     []Mirror
 }
 
 func CreateCluster(ctx context.Context, opts CreateOptions) error {
@@ -72,6 +87,20 @@ func CreateCluster(ctx context.Context, opts CreateOptions) error {
 	if opts.IngressPort > 0 {
 		args = append(args, "--port", fmt.Sprintf("%d:443@loadbalancer", opts.IngressPort))
 	}
+	if opts.Agents > 0 {
+		args = append(args, "--agents", strconv.Itoa(opts.Agents))
+	}
+	for _, volume := range opts.Volumes {
+		args = append(args, "--volume", fmt.Sprintf("%s:%s@all", volume.HostPath, volume.ContainerPath))
+	}
+	if len(opts.Mirrors) > 0 {
+		path, err := writeRegistryConfig(opts.Mirrors)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path)
+		args = append(args, "--registry-config", path)
+	}
 	cmd := exec.CommandContext(ctx, binaryName, args...)
 	cmd.Stdout = nil
 	cmd.Stderr = nil
@@ -81,6 +110,33 @@ func CreateCluster(ctx context.Context, opts CreateOptions) error {
 	return nil
 }
 
+// writeRegistryConfig writes registryConfig to a temp file for k3d.
+func writeRegistryConfig(mirrors []Mirror) (string, error) {
+	content := registryConfig(mirrors)
+	file, err := os.CreateTemp("", "kplane-k3d-registries-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("create k3d registry config: %w", err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("write k3d registry config: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("close k3d registry config: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// registryConfig renders the k3s registries.yaml that sends pulls for each
+// upstream to its mirror.
+func registryConfig(mirrors []Mirror) string {
+	content := "mirrors:\n"
+	for _, mirror := range mirrors {
+		content += fmt.Sprintf("  %q:\n    endpoint:\n      - %q\n", mirror.Upstream, mirror.Endpoint)
+	}
+	return content
+}
+
 func DeleteCluster(ctx context.Context, name string) error {
 	cmd := exec.CommandContext(ctx, binaryName, "cluster", "delete", name)
 	if err := cmd.Run(); err != nil {
@@ -99,3 +155,30 @@ func GetKubeconfig(ctx context.Context, name string) ([]byte, error) {
 	}
 	return stdout.Bytes(), nil
 }
+
+func LoadImageArchive(ctx context.Context, name, path string) error {
+	cmd := exec.CommandContext(ctx, binaryName, "image", "import", path, "--cluster", name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("import image archive: %s", msg)
+		}
+		return fmt.Errorf("import image archive: %w", err)
+	}
+	return nil
+}
+
+func LoadImages(ctx context.Context, name string, images []string) error {
+	args := append([]string{"image", "import"}, images...)
+	cmd := exec.CommandContext(ctx, binaryName, append(args, "--cluster", name)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("import images: %s", msg)
+		}
+		return fmt.Errorf("import images: %w", err)
+	}
+	return nil
+}
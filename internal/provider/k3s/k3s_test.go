@@ -0,0 +1,64 @@
+package k3s
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestRegistryConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors []Mirror
+		want    map[string]any
+	}{
+		{name: "none", want: map[string]any{"mirrors": nil}},
+		{
+			name: "several",
+			mirrors: []Mirror{
+				{Upstream: "docker.io", Endpoint: "http://kplane-mirror-docker-io:5000"},
+				{Upstream: "registry.k8s.io", Endpoint: "http://kplane-mirror-registry-k8s-io:5000"},
+			},
+			want: map[string]any{"mirrors": map[string]any{
+				"docker.io":       map[string]any{"endpoint": []any{"http://kplane-mirror-docker-io:5000"}},
+				"registry.k8s.io": map[string]any{"endpoint": []any{"http://kplane-mirror-registry-k8s-io:5000"}},
+			}},
+		},
+		{
+			name:    "upstream with port",
+			mirrors: []Mirror{{Upstream: "localhost:5001", Endpoint: "http://kplane-mirror-localhost-5001:5000"}},
+			want: map[string]any{"mirrors": map[string]any{
+				"localhost:5001": map[string]any{"endpoint": []any{"http://kplane-mirror-localhost-5001:5000"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			if err := yaml.Unmarshal([]byte(registryConfig(tt.mirrors)), &got); err != nil {
+				t.Fatalf("registries.yaml does not parse: %v\n%s", err, registryConfig(tt.mirrors))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("registryConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteRegistryConfig(t *testing.T) {
+	mirrors := []Mirror{{Upstream: "quay.io", Endpoint: "http://kplane-mirror-quay-io:5000"}}
+	path, err := writeRegistryConfig(mirrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != registryConfig(mirrors) {
+		t.Errorf("wrote %q, want %q", data, registryConfig(mirrors))
+	}
+}
@@ -37,10 +37,21 @@ func (p *Provider) ListClusters(ctx context.Context) ([]string, error) {
 }
 
 func (p *Provider) CreateCluster(ctx context.Context, opts provider.CreateClusterOptions) error {
+	volumes := make([]Volume, 0, len(opts.Mounts))
+	for _, mount := range opts.Mounts {
+		volumes = append(volumes, Volume{HostPath: mount.HostPath, ContainerPath: mount.ContainerPath})
+	}
+	mirrors := make([]Mirror, 0, len(opts.Mirrors))
+	for _, mirror := range opts.Mirrors {
+		mirrors = append(mirrors, Mirror{Upstream: mirror.Upstream, Endpoint: mirror.Endpoint})
+	}
 	return CreateCluster(ctx, CreateOptions{
 		Name:        opts.Name,
 		Image:       opts.NodeImage,
 		IngressPort: opts.IngressPort,
+		Agents:      opts.Workers,
+		Volumes:     volumes,
+		Mirrors:     mirrors,
 	})
 }
 
@@ -51,3 +62,11 @@ func (p *Provider) DeleteCluster(ctx context.Context, name string) error {
 func (p *Provider) GetKubeconfig(ctx context.Context, name string) ([]byte, error) {
 	return GetKubeconfig(ctx, name)
 }
+
+func (p *Provider) LoadImageArchive(ctx context.Context, name, path string) error {
+	return LoadImageArchive(ctx, name, path)
+}
+
+func (p *Provider) LoadImages(ctx context.Context, name string, images []string) error {
+	return LoadImages(ctx, name, images)
+}
@@ -202,3 +202,38 @@ func GetKubeconfig(ctx context.Context, name string) ([]byte, error) {
 	}
 	return stdout.Bytes(), nil
 }
+
+func LoadImageArchive(ctx context.Context, name, path string) error {
+	bin, err := resolveBinary()
+	if err != nil {
+		return fmt.Errorf("load image archive: kind not installed; install from https://kind.sigs.k8s.io/")
+	}
+	cmd := exec.CommandContext(ctx, bin, "load", "image-archive", path, "--name", name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("load image archive: %s", msg)
+		}
+		return fmt.Errorf("load image archive: %w", err)
+	}
+	return nil
+}
+
+func LoadImages(ctx context.Context, name string, images []string) error {
+	bin, err := resolveBinary()
+	if err != nil {
+		return fmt.Errorf("load images: kind not installed; install from https://kind.sigs.k8s.io/")
+	}
+	args := append([]string{"load", "docker-image"}, images...)
+	cmd := exec.CommandContext(ctx, bin, append(args, "--name", name)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("load images: %s", msg)
+		}
+		return fmt.Errorf("load images: %w", err)
+	}
+	return nil
+}
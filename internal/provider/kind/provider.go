@@ -51,3 +51,11 @@ func (p *Provider) DeleteCluster(ctx context.Context, name string) error {
 func (p *Provider) GetKubeconfig(ctx context.Context, name string) ([]byte, error) {
 	return GetKubeconfig(ctx, name)
 }
+
+func (p *Provider) LoadImageArchive(ctx context.Context, name, path string) error {
+	return LoadImageArchive(ctx, name, path)
+}
+
+func (p *Provider) LoadImages(ctx context.Context, name string, images []string) error {
+	return LoadImages(ctx, name, images)
+}
@@ -0,0 +1,74 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func EnsureInstalled(path string) error {
+	if _, err := load(path); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ContextExists(_ context.Context, path, name string) (bool, error) {
+	cfg, err := load(path)
+	if err != nil {
+		return false, err
+	}
+	_, ok := cfg.Contexts[name]
+	return ok, nil
+}
+
+func CurrentContext(path string) (string, error) {
+	cfg, err := load(path)
+	if err != nil {
+		return "", err
+	}
+	return cfg.CurrentContext, nil
+}
+
+func GetKubeconfig(_ context.Context, path, name string) ([]byte, error) {
+	cfg, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cfg.Contexts[name]; !ok {
+		return nil, fmt.Errorf("get kubeconfig: context %q not found", name)
+	}
+	cfg.CurrentContext = name
+	if err := clientcmdapi.MinifyConfig(cfg); err != nil {
+		return nil, fmt.Errorf("get kubeconfig: %w", err)
+	}
+	if err := clientcmdapi.FlattenConfig(cfg); err != nil {
+		return nil, fmt.Errorf("get kubeconfig: %w", err)
+	}
+	out, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("serialize kubeconfig: %w", err)
+	}
+	return out, nil
+}
+
+func load(path string) (*clientcmdapi.Config, error) {
+	if path == "" {
+		cfg, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+		if err != nil {
+			return nil, fmt.Errorf("load kubeconfig: %w", err)
+		}
+		return cfg, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("kubeconfig %s not found; set kubeconfig.path in your profile", path)
+	}
+	cfg, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig %s: %w", path, err)
+	}
+	return cfg, nil
+}
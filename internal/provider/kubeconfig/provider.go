@@ -0,0 +1,79 @@
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kplane-dev/kplane/internal/provider"
+)
+
+type Provider struct {
+	path    string
+	context string
+}
+
+func New(path, contextName string) *Provider {
+	return &Provider{path: path, context: contextName}
+}
+
+func (p *Provider) Name() string {
+	return "kubeconfig"
+}
+
+func (p *Provider) ContextPrefix() string {
+	return ""
+}
+
+func (p *Provider) ContextName(clusterName string) string {
+	if p.context != "" {
+		return p.context
+	}
+	return clusterName
+}
+
+func (p *Provider) EnsureInstalled() error {
+	return EnsureInstalled(p.path)
+}
+
+func (p *Provider) ClusterExists(ctx context.Context, name string) (bool, error) {
+	return ContextExists(ctx, p.path, p.ContextName(name))
+}
+
+func (p *Provider) ListClusters(ctx context.Context) ([]string, error) {
+	name := p.context
+	if name == "" {
+		current, err := CurrentContext(p.path)
+		if err != nil {
+			return nil, err
+		}
+		name = current
+	}
+	if name == "" {
+		return nil, nil
+	}
+	exists, err := ContextExists(ctx, p.path, name)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return []string{name}, nil
+}
+
+func (p *Provider) CreateCluster(_ context.Context, opts provider.CreateClusterOptions) error {
+	return fmt.Errorf("context %q not found; the kubeconfig provider only uses existing clusters", p.ContextName(opts.Name))
+}
+
+func (p *Provider) DeleteCluster(_ context.Context, name string) error {
+	return fmt.Errorf("the kubeconfig provider does not delete existing clusters; remove the kplane stack from %q manually", p.ContextName(name))
+}
+
+func (p *Provider) GetKubeconfig(ctx context.Context, name string) ([]byte, error) {
+	return GetKubeconfig(ctx, p.path, p.ContextName(name))
+}
+
+func (p *Provider) LoadImageArchive(_ context.Context, name, _ string) error {
+	return fmt.Errorf("the kubeconfig provider cannot load images into %q; push them to a registry its nodes can reach", p.ContextName(name))
+}
+
+func (p *Provider) LoadImages(_ context.Context, name string, _ []string) error {
+	return fmt.Errorf("the kubeconfig provider cannot load images into %q; push them to a registry its nodes can reach", p.ContextName(name))
+}
@@ -2,11 +2,30 @@ package provider
 
 import "context"
 
+type Mount struct {
+	HostPath      string
+	ContainerPath string
+}
+
+// Mirror sends pulls for an upstream registry to a cache at Endpoint.
+type Mirror struct {
+	Upstream string
+	Endpoint string
+}
+
 type CreateClusterOptions struct {
 	Name        string
 	NodeImage   string
 	ConfigPath  string
 	IngressPort int
+	// Workers adds worker nodes next to the control-plane node.
+	Workers int
+	// Mounts bind host directories into every cluster node. Kind reads
+	// workers and mounts from the config at ConfigPath.
+	Mounts []Mount
+	// Mirrors configure the nodes' container runtime. Kind reads them from
+	// the config at ConfigPath.
+	Mirrors []Mirror
 }
 
 type Provider interface {
@@ -19,4 +38,8 @@ type Provider interface {
 	CreateCluster(ctx context.Context, opts CreateClusterOptions) error
 	DeleteCluster(ctx context.Context, name string) error
 	GetKubeconfig(ctx context.Context, name string) ([]byte, error)
+	// LoadImageArchive imports a `docker save` tarball into every node.
+	LoadImageArchive(ctx context.Context, name, path string) error
+	// LoadImages copies images from the local Docker daemon into every node.
+	LoadImages(ctx context.Context, name string, images []string) error
 }
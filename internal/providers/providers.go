@@ -3,17 +3,26 @@ package providers
 import (
 	"fmt"
 
+	"github.com/kplane-dev/kplane/internal/config"
 	"github.com/kplane-dev/kplane/internal/provider"
 	k3sprovider "github.com/kplane-dev/kplane/internal/provider/k3s"
 	kindprovider "github.com/kplane-dev/kplane/internal/provider/kind"
+	kubeconfigprovider "github.com/kplane-dev/kplane/internal/provider/kubeconfig"
 )
 
 func New(name string) (provider.Provider, error) {
+	return NewForProfile(name, config.Profile{})
+}
+
+func NewForProfile(name string, profile config.Profile) (provider.Provider, error) {
 	if name == "" || name == "kind" {
 		return kindprovider.New(), nil
 	}
 	if name == "k3s" || name == "k3d" {
 		return k3sprovider.New(), nil
 	}
+	if name == "kubeconfig" {
+		return kubeconfigprovider.New(profile.Kubeconfig.Path, profile.Kubeconfig.Context), nil
+	}
 	return nil, fmt.Errorf("unsupported provider %q", name)
 }
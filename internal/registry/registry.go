@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// Image runs each mirror as a pull-through cache.
+	Image = "registry:2"
+	port  = 5000
+)
+
+// DefaultUpstreams are the registries the stacks pull from.
+var DefaultUpstreams = []string{"docker.io", "registry.k8s.io", "ghcr.io", "quay.io"}
+
+// Mirror is a registry:2 container proxying one upstream registry. Its cache
+// lives in a volume of the same name, so it outlives management clusters.
+type Mirror struct {
+	Upstream  string
+	Container string
+}
+
+func Mirrors(upstreams []string) []Mirror {
+	if len(upstreams) == 0 {
+		upstreams = DefaultUpstreams
+	}
+	mirrors := make([]Mirror, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		mirrors = append(mirrors, Mirror{
+			Upstream:  upstream,
+			Container: "kplane-mirror-" + strings.NewReplacer(".", "-", ":", "-").Replace(upstream),
+		})
+	}
+	return mirrors
+}
+
+// Endpoint is the address cluster nodes pull through once the mirror shares
+// their Docker network.
+func (m Mirror) Endpoint() string {
+	return fmt.Sprintf("http://%s:%d", m.Container, port)
+}
+
+func (m Mirror) remoteURL() string {
+	if m.Upstream == "docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + m.Upstream
+}
+
+// Ensure starts every mirror, creating the containers that do not exist.
+func Ensure(ctx context.Context, mirrors []Mirror) error {
+	for _, m := range mirrors {
+		state, err := docker(ctx, "container", "inspect", "--format", "{{.State.Running}}", m.Container)
+		switch {
+		case err != nil:
+			if _, err := docker(ctx, "run", "-d", "--restart=always",
+				"--name", m.Container,
+				"--label", "dev.kplane.mirror="+m.Upstream,
+				"-e", "REGISTRY_PROXY_REMOTEURL="+m.remoteURL(),
+				"-v", m.Container+":/var/lib/registry",
+				Image); err != nil {
+				return fmt.Errorf("start mirror for %s: %w", m.Upstream, err)
+			}
+		case state != "true":
+			if _, err := docker(ctx, "start", m.Container); err != nil {
+				return fmt.Errorf("start mirror for %s: %w", m.Upstream, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Connect attaches the mirrors to a cluster's Docker network.
+func Connect(ctx context.Context, network string, mirrors []Mirror) error {
+	for _, m := range mirrors {
+		if _, err := docker(ctx, "network", "connect", network, m.Container); err != nil && !strings.Contains(err.Error(), "already exists") {
+			return fmt.Errorf("connect mirror for %s to %s: %w", m.Upstream, network, err)
+		}
+	}
+	return nil
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMirrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams []string
+		want      []Mirror
+	}{
+		{
+			name: "defaults",
+			want: []Mirror{
+				{Upstream: "docker.io", Container: "kplane-mirror-docker-io"},
+				{Upstream: "registry.k8s.io", Container: "kplane-mirror-registry-k8s-io"},
+				{Upstream: "ghcr.io", Container: "kplane-mirror-ghcr-io"},
+				{Upstream: "quay.io", Container: "kplane-mirror-quay-io"},
+			},
+		},
+		{
+			name:      "explicit",
+			upstreams: []string{"quay.io"},
+			want:      []Mirror{{Upstream: "quay.io", Container: "kplane-mirror-quay-io"}},
+		},
+		{
+			name:      "port",
+			upstreams: []string{"registry.lan:5001"},
+			want:      []Mirror{{Upstream: "registry.lan:5001", Container: "kplane-mirror-registry-lan-5001"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mirrors(tt.upstreams); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mirrors(%v) = %v, want %v", tt.upstreams, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrorEndpoints(t *testing.T) {
+	tests := []struct {
+		upstream string
+		endpoint string
+		remote   string
+	}{
+		{upstream: "docker.io", endpoint: "http://kplane-mirror-docker-io:5000", remote: "https://registry-1.docker.io"},
+		{upstream: "ghcr.io", endpoint: "http://kplane-mirror-ghcr-io:5000", remote: "https://ghcr.io"},
+		{upstream: "registry.lan:5001", endpoint: "http://kplane-mirror-registry-lan-5001:5000", remote: "https://registry.lan:5001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upstream, func(t *testing.T) {
+			m := Mirrors([]string{tt.upstream})[0]
+			if got := m.Endpoint(); got != tt.endpoint {
+				t.Errorf("Endpoint() = %q, want %q", got, tt.endpoint)
+			}
+			if got := m.remoteURL(); got != tt.remote {
+				t.Errorf("remoteURL() = %q, want %q", got, tt.remote)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package latest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+)
+
+const (
+	AuthPolicyManaged = "managed"
+	AuthPolicyBasic   = "basic"
+
+	DefaultIssuerTemplate = "https://{externalHost}"
+)
+
+type Auth struct {
+	Policy         string
+	IssuerTemplate string
+	OIDC           *OIDC
+}
+
+type OIDC struct {
+	IssuerURL      string
+	ClientID       string
+	UsernameClaim  string
+	UsernamePrefix string
+	GroupsClaim    string
+	GroupsPrefix   string
+	CAData         []byte
+}
+
+// ManagedIssuer reports whether the policy selects the managed issuer, which
+// an empty policy defaults to.
+func (a Auth) ManagedIssuer() bool {
+	return a.Policy == "" || a.Policy == AuthPolicyManaged || a.Policy == "ManagedIssuer"
+}
+
+// AuthSpec renders the ControlPlaneClass `auth` block for a profile policy.
+// An empty policy selects the managed issuer.
+func AuthSpec(auth Auth) (map[string]any, error) {
+	var spec map[string]any
+	switch auth.Policy {
+	case "", AuthPolicyManaged, "ManagedIssuer":
+		issuer := auth.IssuerTemplate
+		if issuer == "" {
+			issuer = DefaultIssuerTemplate
+		}
+		spec = map[string]any{
+			"policy":         "ManagedIssuer",
+			"issuerTemplate": issuer,
+			"defaultRole":    "admin",
+		}
+	case AuthPolicyBasic:
+		spec = map[string]any{
+			"model":       "basic",
+			"defaultRole": "admin",
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth policy %q (use %s or %s)", auth.Policy, AuthPolicyManaged, AuthPolicyBasic)
+	}
+	if auth.OIDC != nil {
+		oidc, err := oidcSpec(*auth.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		spec["oidc"] = oidc
+	}
+	return spec, nil
+}
+
+func oidcSpec(oidc OIDC) (map[string]any, error) {
+	issuer, err := url.Parse(oidc.IssuerURL)
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		return nil, fmt.Errorf("oidc issuer URL must be an https URL, got %q", oidc.IssuerURL)
+	}
+	if oidc.ClientID == "" {
+		return nil, fmt.Errorf("oidc client ID is required")
+	}
+	spec := map[string]any{
+		"issuerURL": oidc.IssuerURL,
+		"clientID":  oidc.ClientID,
+	}
+	optional := map[string]string{
+		"usernameClaim":  oidc.UsernameClaim,
+		"usernamePrefix": oidc.UsernamePrefix,
+		"groupsClaim":    oidc.GroupsClaim,
+		"groupsPrefix":   oidc.GroupsPrefix,
+	}
+	for key, value := range optional {
+		if value != "" {
+			spec[key] = value
+		}
+	}
+	if len(oidc.CAData) > 0 {
+		spec["caData"] = base64.StdEncoding.EncodeToString(oidc.CAData)
+	}
+	return spec, nil
+}
+
+// OIDCFromSpec reads the `auth.oidc` block of a ControlPlaneClass. It returns
+// nil when the class does not configure OIDC.
+func OIDCFromSpec(auth map[string]any) (*OIDC, error) {
+	raw, ok := auth["oidc"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	value := func(key string) string {
+		s, _ := raw[key].(string)
+		return s
+	}
+	oidc := &OIDC{
+		IssuerURL:      value("issuerURL"),
+		ClientID:       value("clientID"),
+		UsernameClaim:  value("usernameClaim"),
+		UsernamePrefix: value("usernamePrefix"),
+		GroupsClaim:    value("groupsClaim"),
+		GroupsPrefix:   value("groupsPrefix"),
+	}
+	if caData := value("caData"); caData != "" {
+		data, err := base64.StdEncoding.DecodeString(caData)
+		if err != nil {
+			return nil, fmt.Errorf("decode oidc caData: %w", err)
+		}
+		oidc.CAData = data
+	}
+	return oidc, nil
+}
@@ -0,0 +1,64 @@
+package latest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    Auth
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "default is managed",
+			want: map[string]any{"policy": "ManagedIssuer", "issuerTemplate": DefaultIssuerTemplate, "defaultRole": "admin"},
+		},
+		{
+			name: "basic",
+			auth: Auth{Policy: AuthPolicyBasic, IssuerTemplate: "https://ignored"},
+			want: map[string]any{"model": "basic", "defaultRole": "admin"},
+		},
+		{
+			name: "managed",
+			auth: Auth{Policy: AuthPolicyManaged},
+			want: map[string]any{"policy": "ManagedIssuer", "issuerTemplate": DefaultIssuerTemplate, "defaultRole": "admin"},
+		},
+		{
+			name: "managed with template",
+			auth: Auth{Policy: "ManagedIssuer", IssuerTemplate: "https://{externalHost}/issuer"},
+			want: map[string]any{"policy": "ManagedIssuer", "issuerTemplate": "https://{externalHost}/issuer", "defaultRole": "admin"},
+		},
+		{
+			name:    "unknown",
+			auth:    Auth{Policy: "ldap"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AuthSpec(tt.auth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthManagedIssuer(t *testing.T) {
+	for policy, want := range map[string]bool{
+		"":                true,
+		AuthPolicyManaged: true,
+		"ManagedIssuer":   true,
+		AuthPolicyBasic:   false,
+	} {
+		if got := (Auth{Policy: policy}).ManagedIssuer(); got != want {
+			t.Errorf("Auth{Policy: %q}.ManagedIssuer() = %v, want %v", policy, got, want)
+		}
+	}
+}
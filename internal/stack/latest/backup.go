@@ -0,0 +1,398 @@
+package latest
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// BackupFormatVersion 2 adds the management cluster objects.
+	BackupFormatVersion = 2
+
+	backupMetadataFile       = "metadata.json"
+	backupSnapshotFile       = "etcd/snapshot.db"
+	backupOperatorConfigFile = "operator-config.yaml"
+	backupSecretsDir         = "secrets/"
+	backupObjectsDir         = "objects/"
+
+	restoreHelperImage = "docker.io/library/busybox:1.36"
+)
+
+type BackupMetadata struct {
+	FormatVersion int         `json:"formatVersion"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	Namespace     string      `json:"namespace"`
+	StackVersion  string      `json:"stackVersion,omitempty"`
+	IngressPort   int         `json:"ingressPort,omitempty"`
+	Images        Images      `json:"images"`
+	EtcdMembers   int         `json:"etcdMembers"`
+	EtcdStorage   EtcdStorage `json:"etcdStorage"`
+	SnapshotBytes int64       `json:"snapshotBytes"`
+	Objects       int         `json:"objects"`
+}
+
+type BackupOptions struct {
+	Context   string
+	Namespace string
+	// Metadata carries the CLI-side fields (stack version, ingress port).
+	Metadata BackupMetadata
+	Logf     func(format string, args ...any)
+}
+
+// Backup streams an etcd snapshot through the etcd gRPC gateway behind the
+// service proxy, then writes it to w as a tar.gz together with the PKI
+// secrets, the operator config and metadata describing the installation.
+func Backup(ctx context.Context, opts BackupOptions, w io.Writer) (*BackupMetadata, error) {
+	installOpts := InstallOptions{Context: opts.Context, Namespace: opts.Namespace, Logf: opts.Logf}
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := opts.Metadata
+	metadata.FormatVersion = BackupFormatVersion
+	metadata.CreatedAt = time.Now().UTC()
+	metadata.Namespace = opts.Namespace
+	for _, w := range workloads {
+		image, err := workloadImage(ctx, c, opts.Namespace, w)
+		if err != nil {
+			return nil, err
+		}
+		switch w.container {
+		case "etcd":
+			metadata.Images.Etcd = image
+		case "apiserver":
+			metadata.Images.Apiserver = image
+		case "manager":
+			metadata.Images.Operator = image
+		}
+	}
+	storage, members, ok, err := installedEtcd(ctx, installOpts)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("etcd statefulset not found in %s; run kplane up or kplane upgrade first", opts.Namespace)
+	}
+	metadata.EtcdStorage, metadata.EtcdMembers = storage, members
+
+	snapshot, err := os.CreateTemp("", "kplane-etcd-snapshot-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot file: %w", err)
+	}
+	defer os.Remove(snapshot.Name())
+	defer snapshot.Close()
+	logf(installOpts, "taking etcd snapshot")
+	size, err := streamSnapshot(ctx, c, opts.Namespace, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	metadata.SnapshotBytes = size
+	if _, err := snapshot.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("rewind snapshot: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	logf(installOpts, "archiving PKI secrets and operator config")
+	for _, name := range pkiSecrets {
+		secret, err := c.Get(ctx, "secret", name, opts.Namespace)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		data, err := yaml.Marshal(map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]any{"name": name},
+			"type":       secret.Object["type"],
+			"data":       secret.Object["data"],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("encode secret %s: %w", name, err)
+		}
+		if err := writeTarFile(tw, backupSecretsDir+name+".yaml", data); err != nil {
+			return nil, err
+		}
+	}
+	operatorConfig, err := c.Get(ctx, "configmap", "operator-config", opts.Namespace)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		data, err := yaml.Marshal(map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": "operator-config"},
+			"data":       operatorConfig.Object["data"],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("encode operator config: %w", err)
+		}
+		if err := writeTarFile(tw, backupOperatorConfigFile, data); err != nil {
+			return nil, err
+		}
+	}
+	logf(installOpts, "archiving controlplanes, classes, endpoints and their kubeconfigs")
+	objects, err := managementObjects(ctx, c, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		name := fmt.Sprintf("%s%03d-%s-%s.yaml", backupObjectsDir, i, strings.ToLower(obj.GetKind()), obj.GetName())
+		if err := writeTarFile(tw, name, data); err != nil {
+			return nil, err
+		}
+	}
+	metadata.Objects = len(objects)
+	if err := tw.WriteHeader(&tar.Header{Name: backupSnapshotFile, Mode: 0o600, Size: size, ModTime: metadata.CreatedAt}); err != nil {
+		return nil, fmt.Errorf("write archive: %w", err)
+	}
+	if _, err := io.Copy(tw, snapshot); err != nil {
+		return nil, fmt.Errorf("write archive: %w", err)
+	}
+	meta, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode metadata: %w", err)
+	}
+	if err := writeTarFile(tw, backupMetadataFile, meta); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("write archive: %w", err)
+	}
+	return &metadata, nil
+}
+
+// managementObjects collects what registers VCPs on the management cluster
+// rather than in kplane-etcd: classes (including derived per-VCP ones),
+// controlplanes, endpoints, the kubeconfig secrets the controlplanes point at
+// and the CLI's state ConfigMaps. They are cleaned like exported objects and
+// ordered so each applies after what it references.
+func managementObjects(ctx context.Context, c *kubectl.Client, namespace string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	var controlPlanes []unstructured.Unstructured
+	for _, resource := range []string{"controlplaneclass", "controlplane", "controlplaneendpoint"} {
+		list, err := c.List(ctx, resource, "", metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+		if resource == "controlplane" {
+			controlPlanes = list.Items
+		}
+	}
+	seen := map[string]bool{}
+	for _, cp := range controlPlanes {
+		name, _, _ := unstructured.NestedString(cp.Object, "status", "kubeconfigSecretRef", "name")
+		secretNamespace, _, _ := unstructured.NestedString(cp.Object, "status", "kubeconfigSecretRef", "namespace")
+		if name == "" || secretNamespace == "" || seen[secretNamespace+"/"+name] || (secretNamespace == namespace && slices.Contains(pkiSecrets, name)) {
+			continue
+		}
+		seen[secretNamespace+"/"+name] = true
+		secret, err := c.Get(ctx, "secret", name, secretNamespace)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		objects = append(objects, secret)
+	}
+	for _, name := range []string{"kplane-credentials", "kplane-management"} {
+		configMap, err := c.Get(ctx, "configmap", name, namespace)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		objects = append(objects, configMap)
+	}
+	for _, obj := range objects {
+		kubectl.CleanObject(obj)
+	}
+	return objects, nil
+}
+
+// streamSnapshot calls Maintenance.Snapshot on the etcd gRPC gateway. The
+// gateway answers with one JSON message per chunk.
+func streamSnapshot(ctx context.Context, c *kubectl.Client, namespace string, out io.Writer) (int64, error) {
+	stream, err := c.Typed().CoreV1().RESTClient().Post().
+		Namespace(namespace).
+		Resource("services").
+		Name("http:kplane-etcd:2379").
+		SubResource("proxy").
+		Suffix("v3/maintenance/snapshot").
+		Body([]byte("{}")).
+		Stream(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("etcd snapshot: %w", err)
+	}
+	defer stream.Close()
+	decoder := json.NewDecoder(stream)
+	var total int64
+	for {
+		var message struct {
+			Result *struct {
+				Blob []byte `json:"blob"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, fmt.Errorf("etcd snapshot: %w", err)
+		}
+		if message.Error != nil {
+			return 0, fmt.Errorf("etcd snapshot: %s", message.Error.Message)
+		}
+		if message.Result == nil {
+			continue
+		}
+		n, err := out.Write(message.Result.Blob)
+		if err != nil {
+			return 0, fmt.Errorf("write snapshot: %w", err)
+		}
+		total += int64(n)
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("etcd snapshot: empty response")
+	}
+	return total, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+// Archive is an extracted backup. SnapshotPath points at a temp file that
+// Close removes.
+type Archive struct {
+	Metadata       BackupMetadata
+	SnapshotPath   string
+	Secrets        map[string][]byte
+	OperatorConfig []byte
+	// Objects are the management cluster objects in apply order.
+	Objects [][]byte
+}
+
+func (a *Archive) Close() error {
+	if a.SnapshotPath == "" {
+		return nil
+	}
+	return os.Remove(a.SnapshotPath)
+}
+
+func ReadBackup(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read backup: %w", err)
+	}
+	defer gz.Close()
+	archive := &Archive{Secrets: map[string][]byte{}}
+	foundMetadata := false
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = archive.Close()
+			return nil, fmt.Errorf("read backup: %w", err)
+		}
+		switch name := path.Clean(header.Name); {
+		case name == backupSnapshotFile:
+			file, err := os.CreateTemp("", "kplane-etcd-snapshot-*.db")
+			if err != nil {
+				return nil, fmt.Errorf("extract snapshot: %w", err)
+			}
+			archive.SnapshotPath = file.Name()
+			_, err = io.Copy(file, tr)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = archive.Close()
+				return nil, fmt.Errorf("extract snapshot: %w", err)
+			}
+		case name == backupMetadataFile:
+			data, err := io.ReadAll(tr)
+			if err == nil {
+				err = json.Unmarshal(data, &archive.Metadata)
+			}
+			if err != nil {
+				_ = archive.Close()
+				return nil, fmt.Errorf("read backup metadata: %w", err)
+			}
+			foundMetadata = true
+		case name == backupOperatorConfigFile:
+			if archive.OperatorConfig, err = io.ReadAll(tr); err != nil {
+				_ = archive.Close()
+				return nil, fmt.Errorf("read backup: %w", err)
+			}
+		case strings.HasPrefix(name, backupObjectsDir):
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				_ = archive.Close()
+				return nil, fmt.Errorf("read backup: %w", err)
+			}
+			archive.Objects = append(archive.Objects, data)
+		case strings.HasPrefix(name, backupSecretsDir):
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				_ = archive.Close()
+				return nil, fmt.Errorf("read backup: %w", err)
+			}
+			archive.Secrets[strings.TrimSuffix(strings.TrimPrefix(name, backupSecretsDir), ".yaml")] = data
+		}
+	}
+	switch {
+	case !foundMetadata:
+		_ = archive.Close()
+		return nil, fmt.Errorf("backup has no %s", backupMetadataFile)
+	case archive.Metadata.FormatVersion > BackupFormatVersion:
+		_ = archive.Close()
+		return nil, fmt.Errorf("backup format %d is newer than this kplane supports (%d)", archive.Metadata.FormatVersion, BackupFormatVersion)
+	case archive.SnapshotPath == "":
+		return nil, fmt.Errorf("backup has no %s", backupSnapshotFile)
+	}
+	return archive, nil
+}
@@ -0,0 +1,118 @@
+package latest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+type backupFile struct {
+	name string
+	data []byte
+}
+
+func backupArchive(t *testing.T, files ...backupFile) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := writeTarFile(tw, f.name, f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func backupMetadata(t *testing.T, formatVersion int) backupFile {
+	t.Helper()
+	data, err := json.Marshal(BackupMetadata{FormatVersion: formatVersion, Namespace: "kplane-system", StackVersion: "v0.1", EtcdMembers: 1, Objects: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backupFile{backupMetadataFile, data}
+}
+
+func TestReadBackup(t *testing.T) {
+	snapshot := backupFile{backupSnapshotFile, []byte("snapshot")}
+	tests := []struct {
+		name    string
+		files   []backupFile
+		wantErr string
+		check   func(t *testing.T, a *Archive)
+	}{
+		{
+			name: "full",
+			files: []backupFile{
+				backupMetadata(t, BackupFormatVersion),
+				{backupSecretsDir + clusterSigningSecret + ".yaml", []byte("kind: Secret")},
+				{backupOperatorConfigFile, []byte("kind: ConfigMap")},
+				{backupObjectsDir + "000-controlplaneclass-starter.yaml", []byte("kind: ControlPlaneClass")},
+				{backupObjectsDir + "001-controlplane-demo.yaml", []byte("kind: ControlPlane")},
+				{"./" + backupSnapshotFile, snapshot.data},
+				{"unknown.txt", []byte("ignored")},
+			},
+			check: func(t *testing.T, a *Archive) {
+				if a.Metadata.StackVersion != "v0.1" || a.Metadata.Objects != 2 {
+					t.Errorf("metadata = %+v", a.Metadata)
+				}
+				if string(a.Secrets[clusterSigningSecret]) != "kind: Secret" || len(a.Secrets) != 1 {
+					t.Errorf("secrets = %v", a.Secrets)
+				}
+				if string(a.OperatorConfig) != "kind: ConfigMap" {
+					t.Errorf("operator config = %q", a.OperatorConfig)
+				}
+				if len(a.Objects) != 2 || string(a.Objects[0]) != "kind: ControlPlaneClass" || string(a.Objects[1]) != "kind: ControlPlane" {
+					t.Errorf("objects out of order: %q", a.Objects)
+				}
+				data, err := os.ReadFile(a.SnapshotPath)
+				if err != nil || string(data) != "snapshot" {
+					t.Errorf("snapshot = %q, %v", data, err)
+				}
+			},
+		},
+		{
+			name:  "format 1 without objects",
+			files: []backupFile{backupMetadata(t, 1), snapshot},
+			check: func(t *testing.T, a *Archive) {
+				if len(a.Objects) != 0 {
+					t.Errorf("objects = %q", a.Objects)
+				}
+			},
+		},
+		{name: "no metadata", files: []backupFile{snapshot}, wantErr: "no " + backupMetadataFile},
+		{name: "no snapshot", files: []backupFile{backupMetadata(t, BackupFormatVersion)}, wantErr: "no " + backupSnapshotFile},
+		{name: "newer format", files: []backupFile{backupMetadata(t, BackupFormatVersion+1), snapshot}, wantErr: "newer than this kplane supports"},
+		{name: "bad metadata", files: []backupFile{{backupMetadataFile, []byte("{")}, snapshot}, wantErr: "read backup metadata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := ReadBackup(backupArchive(t, tt.files...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer archive.Close()
+			tt.check(t, archive)
+		})
+	}
+
+	if _, err := ReadBackup(strings.NewReader("not gzip")); err == nil {
+		t.Error("ReadBackup accepted a non-gzip stream")
+	}
+}
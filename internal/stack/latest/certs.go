@@ -0,0 +1,198 @@
+package latest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+)
+
+const (
+	CertComponentCA            = "ca"
+	CertComponentApiserver     = "apiserver"
+	CertComponentKubeletClient = "kubelet-client"
+	CertComponentAdmin         = "admin"
+	CertComponentIngress       = "ingress"
+)
+
+type certSource struct {
+	secret      string
+	key         string
+	deployments []string
+}
+
+var certSources = map[string]certSource{
+	CertComponentCA:            {secret: clusterSigningSecret, key: "ca.crt", deployments: []string{"kplane-apiserver", "kplane-controlplane-controller-manager"}},
+	CertComponentApiserver:     {secret: apiserverTLSSecret, key: "tls.crt", deployments: []string{"kplane-apiserver"}},
+	CertComponentKubeletClient: {secret: kubeletClientSecret, key: "client.crt", deployments: []string{"kplane-apiserver"}},
+	CertComponentAdmin:         {secret: adminClientSecret, key: "tls.crt"},
+	CertComponentIngress:       {secret: ingressTLSSecret, key: "tls.crt"},
+}
+
+// CertComponents lists the certificates managed by the stack, CA first.
+func CertComponents() []string {
+	return []string{CertComponentCA, CertComponentApiserver, CertComponentKubeletClient, CertComponentAdmin, CertComponentIngress}
+}
+
+type CertOptions struct {
+	Context    string
+	Namespace  string
+	Components []string
+	TLSSANs    []string
+	Logf       func(format string, args ...any)
+}
+
+type CertInfo struct {
+	Component   string
+	Secret      string
+	Subject     string
+	Issuer      string
+	DNSNames    []string
+	IPAddresses []string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	Error       string
+}
+
+func CheckCerts(ctx context.Context, opts CertOptions) ([]CertInfo, error) {
+	existing, err := readExistingPKI(ctx, InstallOptions{Context: opts.Context, Namespace: opts.Namespace})
+	if err != nil {
+		return nil, err
+	}
+	components := opts.Components
+	if len(components) == 0 {
+		components = CertComponents()
+	}
+	infos := make([]CertInfo, 0, len(components))
+	for _, component := range components {
+		source, ok := certSources[component]
+		if !ok {
+			return nil, fmt.Errorf("unknown certificate component %q (use %s)", component, strings.Join(CertComponents(), ", "))
+		}
+		info := CertInfo{Component: component, Secret: opts.Namespace + "/" + source.secret}
+		if !existing.has(source.secret, source.key) {
+			info.Error = "secret not found"
+			infos = append(infos, info)
+			continue
+		}
+		cert, err := parseCertPEM(existing[source.secret][source.key])
+		if err != nil {
+			info.Error = err.Error()
+			infos = append(infos, info)
+			continue
+		}
+		info.Subject = cert.Subject.String()
+		info.Issuer = cert.Issuer.String()
+		info.DNSNames = cert.DNSNames
+		for _, ip := range cert.IPAddresses {
+			info.IPAddresses = append(info.IPAddresses, ip.String())
+		}
+		info.NotBefore = cert.NotBefore
+		info.NotAfter = cert.NotAfter
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// RotateCerts re-issues the selected certificates from the stored CA and
+// restarts the workloads that mount them. Rotating the CA re-issues every
+// leaf certificate as well.
+func RotateCerts(ctx context.Context, opts CertOptions) error {
+	if len(opts.Components) == 0 {
+		return fmt.Errorf("at least one certificate component is required")
+	}
+	installOpts := InstallOptions{Context: opts.Context, Namespace: opts.Namespace, Logf: opts.Logf}
+	existing, err := readExistingPKI(ctx, installOpts)
+	if err != nil {
+		return err
+	}
+	if !existing.has(clusterSigningSecret, "ca.crt", "ca.key") {
+		return fmt.Errorf("no CA found in %s/%s; run `kplane up` first", opts.Namespace, clusterSigningSecret)
+	}
+
+	// Keep hosts added at install time from both serving certificates; the
+	// ingress one carries only the defaults plus the user-supplied SANs.
+	extraSANs := preservedSANs(existing, opts.TLSSANs)
+
+	restarts := map[string]bool{}
+	for _, component := range opts.Components {
+		source, ok := certSources[component]
+		if !ok {
+			return fmt.Errorf("unknown certificate component %q (use %s)", component, strings.Join(CertComponents(), ", "))
+		}
+		delete(existing, source.secret)
+		for _, name := range source.deployments {
+			restarts[name] = true
+		}
+		if component == CertComponentCA {
+			for _, other := range certSources {
+				for _, name := range other.deployments {
+					restarts[name] = true
+				}
+			}
+		}
+	}
+
+	certs, err := buildCertBundle(existing, opts.Namespace, extraSANs)
+	if err != nil {
+		return err
+	}
+	logf(installOpts, "applying secrets")
+	if err := applySecrets(ctx, installOpts, certs); err != nil {
+		return err
+	}
+	if err := applyApiserverKubeconfig(ctx, installOpts, certs); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(restarts))
+	for name := range restarts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logf(installOpts, "restarting deployment %s", name)
+		if err := kubectl.RolloutRestart(ctx, opts.Context, opts.Namespace, "deployment", name); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if err := kubectl.RolloutStatus(ctx, opts.Context, opts.Namespace, "deployment", name, 3*time.Minute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preservedSANs merges extra with the SANs of the stored apiserver and ingress
+// certificates, without duplicates.
+func preservedSANs(existing existingPKI, extra []string) []string {
+	var sans []string
+	add := func(host string) {
+		if host != "" && !containsString(sans, host) {
+			sans = append(sans, host)
+		}
+	}
+	for _, host := range extra {
+		add(strings.TrimSpace(host))
+	}
+	for _, secret := range []string{ingressTLSSecret, apiserverTLSSecret} {
+		if !existing.has(secret, "tls.crt") {
+			continue
+		}
+		cert, err := parseCertPEM(existing[secret]["tls.crt"])
+		if err != nil {
+			continue
+		}
+		for _, name := range cert.DNSNames {
+			add(name)
+		}
+		for _, ip := range cert.IPAddresses {
+			add(ip.String())
+		}
+	}
+	return sans
+}
@@ -0,0 +1,36 @@
+package latest
+
+import "testing"
+
+func TestPreservedSANs(t *testing.T) {
+	ca, err := newCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiserverCert, _, err := ca.issue(apiserverTLSTemplate("kplane-system", []string{"api-only.lan", "10.0.0.5"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingressCert, _, err := ca.issue(ingressTLSTemplate([]string{"ingress.lan"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := existingPKI{
+		apiserverTLSSecret: {"tls.crt": apiserverCert},
+		ingressTLSSecret:   {"tls.crt": ingressCert},
+	}
+
+	got := preservedSANs(existing, []string{"new.lan", "ingress.lan"})
+	for _, want := range []string{"new.lan", "ingress.lan", "api-only.lan", "10.0.0.5", "localhost", "127.0.0.1"} {
+		if !containsString(got, want) {
+			t.Errorf("preservedSANs() = %v, missing %s", got, want)
+		}
+	}
+	seen := map[string]bool{}
+	for _, san := range got {
+		if seen[san] {
+			t.Errorf("preservedSANs() = %v, duplicate %s", got, san)
+		}
+		seen[san] = true
+	}
+}
@@ -0,0 +1,421 @@
+package latest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	EtcdStoragePVC      = "pvc"
+	EtcdStorageHostPath = "hostpath"
+
+	DefaultEtcdStorageSize = "2Gi"
+	// HAMembers is the etcd member and apiserver replica count of the HA
+	// topology.
+	HAMembers = 3
+	// EtcdNodePath is where the hostpath mode keeps etcd data on the node.
+	// Providers mount a host directory here so it outlives the cluster.
+	EtcdNodePath = "/var/lib/kplane/etcd"
+
+	etcdPeerService = "kplane-etcd-peer"
+	etcdMigrateJob  = "kplane-etcd-migrate"
+)
+
+type EtcdStorage struct {
+	// Mode is pvc (the default) or hostpath.
+	Mode         string
+	Size         string
+	StorageClass string
+}
+
+func NormalizeEtcdStorage(storage EtcdStorage) (EtcdStorage, error) {
+	switch storage.Mode {
+	case "", EtcdStoragePVC:
+		storage.Mode = EtcdStoragePVC
+	case EtcdStorageHostPath, "hostPath", "host-path":
+		storage.Mode = EtcdStorageHostPath
+	default:
+		return EtcdStorage{}, fmt.Errorf("unsupported etcd storage %q (use %s or %s)", storage.Mode, EtcdStoragePVC, EtcdStorageHostPath)
+	}
+	if storage.Size == "" {
+		storage.Size = DefaultEtcdStorageSize
+	}
+	return storage, nil
+}
+
+func applyEtcd(ctx context.Context, opts InstallOptions) error {
+	storage, err := NormalizeEtcdStorage(opts.EtcdStorage)
+	if err != nil {
+		return err
+	}
+	members := etcdMembers(opts)
+	current, currentMembers, ok, err := installedEtcd(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if ok && (current.Mode != storage.Mode || (current.Mode == EtcdStoragePVC && current.Size != storage.Size)) {
+		logf(opts, "etcd storage is fixed when the statefulset is created; keeping %s %s", current.Mode, current.Size)
+		storage = current
+	}
+	if ok && currentMembers != members {
+		logf(opts, "etcd membership is fixed when the statefulset is created; keeping %d member(s)", currentMembers)
+		members = currentMembers
+	}
+	manifest := etcdManifest(opts, storage, members)
+	if members > 1 {
+		manifest += fmt.Sprintf(`---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: kplane-etcd
+  namespace: %s
+spec:
+  minAvailable: %d
+  selector:
+    matchLabels:
+      app: kplane-etcd
+`, opts.Namespace, members/2+1)
+	} else if err := kubectl.Delete(ctx, opts.Context, "poddisruptionbudget", "kplane-etcd", opts.Namespace); err != nil {
+		return err
+	}
+	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(manifest)})
+}
+
+func etcdMembers(opts InstallOptions) int {
+	if opts.HA {
+		return HAMembers
+	}
+	return 1
+}
+
+// etcdServers lists the client URLs the apiserver dials. With several
+// members every one is named so the apiserver fails over on its own.
+func etcdServers(ctx context.Context, opts InstallOptions) (string, error) {
+	members := etcdMembers(opts)
+	if _, installed, ok, err := installedEtcd(ctx, opts); err != nil {
+		return "", err
+	} else if ok {
+		members = installed
+	}
+	if members == 1 {
+		return fmt.Sprintf("http://kplane-etcd.%s.svc.cluster.local:2379", opts.Namespace), nil
+	}
+	urls := make([]string, 0, members)
+	for i := 0; i < members; i++ {
+		urls = append(urls, fmt.Sprintf("http://%s.%s.%s.svc.cluster.local:2379", etcdMemberName(i), etcdPeerService, opts.Namespace))
+	}
+	return strings.Join(urls, ","), nil
+}
+
+func etcdManifest(opts InstallOptions, storage EtcdStorage, members int) string {
+	volume := fmt.Sprintf(`  volumeClaimTemplates:
+    - metadata:
+        name: data
+        labels:
+          app: kplane-etcd
+      spec:
+        accessModes:
+          - ReadWriteOnce
+%s        resources:
+          requests:
+            storage: %s
+`, storageClassLine(storage.StorageClass, "        "), storage.Size)
+	podVolumes, subPath := "", ""
+	if storage.Mode == EtcdStorageHostPath {
+		if members > 1 {
+			subPath = "              subPathExpr: $(POD_NAME)\n"
+		}
+		volume = ""
+		podVolumes = fmt.Sprintf(`      volumes:
+        - name: data
+          hostPath:
+            path: %s
+            type: DirectoryOrCreate
+`, EtcdNodePath)
+	}
+
+	return fmt.Sprintf(`apiVersion: v1
+kind: Service
+metadata:
+  name: kplane-etcd
+  namespace: %[1]s
+spec:
+  selector:
+    app: kplane-etcd
+  ports:
+    - name: client
+      port: 2379
+      targetPort: 2379
+    - name: peer
+      port: 2380
+      targetPort: 2380
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[2]s
+  namespace: %[1]s
+spec:
+  clusterIP: None
+  publishNotReadyAddresses: true
+  selector:
+    app: kplane-etcd
+  ports:
+    - name: client
+      port: 2379
+      targetPort: 2379
+    - name: peer
+      port: 2380
+      targetPort: 2380
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: kplane-etcd
+  namespace: %[1]s
+spec:
+  serviceName: %[2]s
+  replicas: %[7]d
+  podManagementPolicy: Parallel
+  selector:
+    matchLabels:
+      app: kplane-etcd
+  template:
+    metadata:
+      labels:
+        app: kplane-etcd
+    spec:
+      affinity:
+        podAntiAffinity:
+          preferredDuringSchedulingIgnoredDuringExecution:
+            - weight: 100
+              podAffinityTerm:
+                topologyKey: kubernetes.io/hostname
+                labelSelector:
+                  matchLabels:
+                    app: kplane-etcd
+      containers:
+        - name: etcd
+          image: %[3]s
+          env:
+            - name: POD_NAME
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.name
+            - name: ALLOW_NONE_AUTHENTICATION
+              value: "yes"
+            - name: ETCD_NAME
+              value: "$(POD_NAME)"
+            - name: ETCD_DATA_DIR
+              value: "/var/lib/etcd/data"
+            - name: ETCD_LISTEN_CLIENT_URLS
+              value: "http://0.0.0.0:2379"
+            - name: ETCD_ADVERTISE_CLIENT_URLS
+              value: "http://$(POD_NAME).%[2]s.%[1]s.svc.cluster.local:2379"
+            - name: ETCD_LISTEN_PEER_URLS
+              value: "http://0.0.0.0:2380"
+            - name: ETCD_INITIAL_ADVERTISE_PEER_URLS
+              value: "http://$(POD_NAME).%[2]s.%[1]s.svc.cluster.local:2380"
+            - name: ETCD_INITIAL_CLUSTER
+              value: "%[4]s"
+            - name: ETCD_INITIAL_CLUSTER_STATE
+              value: "new"
+          ports:
+            - containerPort: 2379
+            - containerPort: 2380
+          readinessProbe:
+            httpGet:
+              path: /health
+              port: 2379
+          volumeMounts:
+            - name: data
+              mountPath: /var/lib/etcd
+%[8]s%[5]s%[6]s`, opts.Namespace, etcdPeerService, opts.Images.Etcd, etcdInitialCluster(opts.Namespace, members), podVolumes, volume, members, subPath)
+}
+
+// migrateLegacyEtcd moves the data of the single-replica etcd Deployment that
+// older stacks ran without a volume into the StatefulSet's first member. The
+// apiserver is scaled down so no writes land after the snapshot; installing
+// the apiserver afterwards scales it back up.
+func migrateLegacyEtcd(ctx context.Context, opts InstallOptions) error {
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return err
+	}
+	if _, err := c.Get(ctx, "deployment", "kplane-etcd", opts.Namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if opts.HA {
+		return fmt.Errorf("etcd still runs as the legacy single-replica deployment; run kplane up without --ha once to move it to a statefulset")
+	}
+	storage, err := NormalizeEtcdStorage(opts.EtcdStorage)
+	if err != nil {
+		return err
+	}
+	logf(opts, "moving etcd data from the legacy deployment to persistent storage")
+	if err := c.MergePatch(ctx, "deployment", "kplane-apiserver", opts.Namespace, []byte(`{"spec":{"replicas":0}}`)); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	dataVolume := `        - name: data
+          persistentVolumeClaim:
+            claimName: data-kplane-etcd-0
+`
+	claim := fmt.Sprintf(`apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data-kplane-etcd-0
+  namespace: %s
+  labels:
+    app: kplane-etcd
+spec:
+  accessModes:
+    - ReadWriteOnce
+%s  resources:
+    requests:
+      storage: %s
+---
+`, opts.Namespace, storageClassLine(storage.StorageClass, "  "), storage.Size)
+	if storage.Mode == EtcdStorageHostPath {
+		claim = ""
+		dataVolume = fmt.Sprintf(`        - name: data
+          hostPath:
+            path: %s
+            type: DirectoryOrCreate
+`, EtcdNodePath)
+	}
+	member := etcdMemberName(0)
+	manifest := fmt.Sprintf(`%sapiVersion: batch/v1
+kind: Job
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      restartPolicy: Never
+      initContainers:
+        - name: snapshot
+          image: %s
+          command:
+            - /usr/local/bin/etcdctl
+            - --endpoints=http://kplane-etcd.%s.svc.cluster.local:2379
+            - snapshot
+            - save
+            - /work/snapshot.db
+          volumeMounts:
+            - name: work
+              mountPath: /work
+      containers:
+        - name: restore
+          image: %s
+          command:
+            - /usr/local/bin/etcdutl
+            - snapshot
+            - restore
+            - /work/snapshot.db
+            - --data-dir=/var/lib/etcd/data
+            - --name=%s
+            - --initial-cluster=%s
+            - --initial-advertise-peer-urls=%s
+          volumeMounts:
+            - name: work
+              mountPath: /work
+            - name: data
+              mountPath: /var/lib/etcd
+      volumes:
+        - name: work
+          emptyDir: {}
+%s`, claim, etcdMigrateJob, opts.Namespace,
+		opts.Images.Etcd, opts.Namespace,
+		opts.Images.Etcd, member, etcdInitialCluster(opts.Namespace, 1), etcdPeerURL(opts.Namespace, member),
+		dataVolume,
+	)
+	background := metav1.DeletePropagationBackground
+	if err := c.Typed().BatchV1().Jobs(opts.Namespace).Delete(ctx, etcdMigrateJob, metav1.DeleteOptions{PropagationPolicy: &background}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete job %s: %w", etcdMigrateJob, err)
+	}
+	if err := c.WaitForDeletion(ctx, "job", etcdMigrateJob, opts.Namespace, time.Minute); err != nil {
+		return err
+	}
+	if err := c.Apply(ctx, []byte(manifest)); err != nil {
+		return err
+	}
+	if err := c.WaitForJob(ctx, opts.Namespace, etcdMigrateJob, 5*time.Minute); err != nil {
+		return fmt.Errorf("etcd data migration: %w; the legacy deployment was left in place", err)
+	}
+	if err := c.Typed().BatchV1().Jobs(opts.Namespace).Delete(ctx, etcdMigrateJob, metav1.DeleteOptions{PropagationPolicy: &background}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete job %s: %w", etcdMigrateJob, err)
+	}
+	if err := c.Delete(ctx, "deployment", "kplane-etcd", opts.Namespace); err != nil {
+		return err
+	}
+	return c.WaitForDeletion(ctx, "deployment", "kplane-etcd", opts.Namespace, 2*time.Minute)
+}
+
+// installedEtcd reports the storage and member count of an existing etcd
+// StatefulSet. Neither can change in place.
+func installedEtcd(ctx context.Context, opts InstallOptions) (EtcdStorage, int, bool, error) {
+	obj, err := kubectl.Get(ctx, opts.Context, "statefulset", "kplane-etcd", opts.Namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return EtcdStorage{}, 0, false, nil
+		}
+		return EtcdStorage{}, 0, false, err
+	}
+	members := 1
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	for _, item := range containers {
+		container, _ := item.(map[string]any)
+		env, _, _ := unstructured.NestedSlice(container, "env")
+		for _, e := range env {
+			if entry, _ := e.(map[string]any); entry["name"] == "ETCD_INITIAL_CLUSTER" {
+				value, _ := entry["value"].(string)
+				members = len(strings.Split(value, ","))
+			}
+		}
+	}
+	templates, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+	if len(templates) == 0 {
+		return EtcdStorage{Mode: EtcdStorageHostPath, Size: DefaultEtcdStorageSize}, members, true, nil
+	}
+	template, _ := templates[0].(map[string]any)
+	size, _, _ := unstructured.NestedString(template, "spec", "resources", "requests", "storage")
+	class, _, _ := unstructured.NestedString(template, "spec", "storageClassName")
+	return EtcdStorage{Mode: EtcdStoragePVC, Size: size, StorageClass: class}, members, true, nil
+}
+
+func etcdMemberName(ordinal int) string {
+	return fmt.Sprintf("kplane-etcd-%d", ordinal)
+}
+
+func etcdPeerURL(namespace, member string) string {
+	return fmt.Sprintf("http://%s.%s.%s.svc.cluster.local:2380", member, etcdPeerService, namespace)
+}
+
+func etcdInitialCluster(namespace string, members int) string {
+	peers := make([]string, 0, members)
+	for i := 0; i < members; i++ {
+		member := etcdMemberName(i)
+		peers = append(peers, member+"="+etcdPeerURL(namespace, member))
+	}
+	return strings.Join(peers, ",")
+}
+
+func storageClassLine(class, indent string) string {
+	if class == "" {
+		return ""
+	}
+	return fmt.Sprintf("%sstorageClassName: %s\n", indent, class)
+}
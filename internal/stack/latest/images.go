@@ -0,0 +1,97 @@
+package latest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kplane-dev/kplane/internal/assets"
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// EmbeddedCRDSource selects the CRDs built into the binary instead of a
+// kustomize URL or path.
+const EmbeddedCRDSource = "embedded"
+
+// StackImage is an image the stack runs, keyed by the component using it.
+// Optional images are only pulled by commands such as oidc serve or restore.
+type StackImage struct {
+	Component string
+	Image     string
+	Optional  bool
+}
+
+// StackImages lists every image kplane can deploy with the given component
+// images, including the ingress controller and helper images.
+func StackImages(images Images) ([]StackImage, error) {
+	list := []StackImage{
+		{Component: "etcd", Image: images.Etcd},
+		{Component: "apiserver", Image: images.Apiserver},
+		{Component: "operator", Image: images.Operator},
+	}
+	ingress, err := ingressImages()
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, ingress...)
+	return append(list,
+		StackImage{Component: "oidc-dex", Image: DefaultDexImage, Optional: true},
+		StackImage{Component: "oidc-forwarder", Image: oidcLoopbackImage, Optional: true},
+		StackImage{Component: "restore-helper", Image: restoreHelperImage, Optional: true},
+	), nil
+}
+
+func ingressImages() ([]StackImage, error) {
+	objects, err := kubectl.DecodeManifest(assets.IngressNginx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var images []StackImage
+	for _, obj := range objects {
+		containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		if err != nil {
+			return nil, fmt.Errorf("read ingress-nginx containers: %w", err)
+		}
+		for _, container := range containers {
+			image, _ := container.(map[string]any)["image"].(string)
+			if image == "" || seen[image] {
+				continue
+			}
+			seen[image] = true
+			images = append(images, StackImage{Component: obj.GetName(), Image: image})
+		}
+	}
+	return images, nil
+}
+
+// crdManifest renders the CRDs from a kustomize URL or path, or from the
+// binary for EmbeddedCRDSource.
+func crdManifest(source string) ([]byte, error) {
+	switch source {
+	case "":
+		return nil, fmt.Errorf("crd source is required when install-crds is true")
+	case EmbeddedCRDSource:
+		tempDir, err := os.MkdirTemp("", "kplane-crds-*")
+		if err != nil {
+			return nil, fmt.Errorf("create temp kustomize dir: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+		if err := writeEmbeddedDir(assets.CRDs, "crds", tempDir); err != nil {
+			return nil, err
+		}
+		return kubectl.BuildKustomize(filepath.Join(tempDir, "crds"))
+	default:
+		return kubectl.BuildKustomize(source)
+	}
+}
+
+func applyCRDs(ctx context.Context, opts InstallOptions) error {
+	manifest, err := crdManifest(opts.CRDSource)
+	if err != nil {
+		return err
+	}
+	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: manifest})
+}
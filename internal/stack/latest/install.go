@@ -2,17 +2,9 @@ package latest
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
 	"encoding/base64"
-	"encoding/hex"
-	"encoding/pem"
 	"fmt"
 	"io/fs"
-	"math/big"
-	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +12,8 @@ import (
 
 	"github.com/kplane-dev/kplane/internal/assets"
 	"github.com/kplane-dev/kplane/internal/kubectl"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type Images struct {
@@ -32,28 +26,68 @@ type InstallOptions struct {
 	Context     string
 	Namespace   string
 	Images      Images
+	EtcdStorage EtcdStorage
+	// HA runs a three-member etcd and three apiserver replicas with
+	// disruption budgets.
+	HA          bool
 	CRDSource   string
 	InstallCRDs bool
-	Logf        func(format string, args ...any)
+	// Auth selects the auth policy of the default ControlPlaneClass.
+	Auth Auth
+	// AuthorizationMode is rbac (the default) or always-allow.
+	AuthorizationMode string
+	// TLSSANs are extra DNS names or IPs added to the apiserver certificate.
+	TLSSANs []string
+	// IngressLoadBalancer publishes ingress-nginx through a LoadBalancer
+	// Service instead of hostPort 443 on ingress-ready nodes, for clusters
+	// kplane did not create.
+	IngressLoadBalancer bool
+	// OIDCLoopbackPort adds a forwarder from 127.0.0.1:<port> in the
+	// apiserver pod to the ingress, so a loopback OIDC issuer served by
+	// `kplane oidc serve` resolves from inside the pod.
+	OIDCLoopbackPort string
+	// RegeneratePKI discards the CA, keys and token stored in the namespace
+	// and issues new ones. Existing VCP kubeconfigs stop working.
+	RegeneratePKI bool
+	Logf          func(format string, args ...any)
 }
 
 func Install(ctx context.Context, opts InstallOptions) error {
+	if _, err := NormalizeAuthorizationMode(opts.AuthorizationMode); err != nil {
+		return err
+	}
+	if _, err := AuthSpec(opts.Auth); err != nil {
+		return err
+	}
+
 	logf(opts, "creating namespace %s", opts.Namespace)
 	if err := kubectl.CreateNamespace(ctx, opts.Context, opts.Namespace); err != nil {
 		return err
 	}
 
-	logf(opts, "generating certs and secrets")
-	certs, err := generateCerts()
+	certs, existing, err := loadOrGenerateCerts(ctx, opts)
 	if err != nil {
 		return err
 	}
+	regenerated := opts.RegeneratePKI && existing.has(clusterSigningSecret, "ca.crt")
+	switch {
+	case regenerated:
+		logf(opts, "regenerating certs and secrets")
+	case existing.has(clusterSigningSecret, "ca.crt"):
+		logf(opts, "reusing existing PKI")
+	default:
+		logf(opts, "generating certs and secrets")
+	}
 
 	logf(opts, "applying secrets")
 	if err := applySecrets(ctx, opts, certs); err != nil {
 		return err
 	}
 
+	if err := migrateLegacyEtcd(ctx, opts); err != nil {
+		return err
+	}
+
 	logf(opts, "deploying etcd")
 	if err := applyEtcd(ctx, opts); err != nil {
 		return err
@@ -106,141 +140,16 @@ func Install(ctx context.Context, opts InstallOptions) error {
 		return err
 	}
 
-	return nil
-}
-
-type certBundle struct {
-	ServiceAccountKey    []byte
-	ServiceAccountPub    []byte
-	ClusterCAKey         []byte
-	ClusterCACert        []byte
-	KubeletClientKey     []byte
-	KubeletClientCert    []byte
-	ApiserverTLSKey      []byte
-	ApiserverTLSCert     []byte
-	ApiserverAdminKey    []byte
-	ApiserverAdminCert   []byte
-	ApiserverAdminToken  string
-	ApiserverServerName  string
-	ApiserverServiceAddr string
-}
-
-func generateCerts() (*certBundle, error) {
-	now := time.Now()
-
-	caKey, caKeyPEM, err := generateRSAKey()
-	if err != nil {
-		return nil, err
-	}
-	caTemplate := &x509.Certificate{
-		SerialNumber:          big.NewInt(1),
-		Subject:               pkix.Name{CommonName: "kplane-ca"},
-		NotBefore:             now.Add(-time.Hour),
-		NotAfter:              now.AddDate(10, 0, 0),
-		IsCA:                  true,
-		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
-		BasicConstraintsValid: true,
-	}
-	caCertPEM, caCert, err := signCert(caTemplate, caTemplate, &caKey.PublicKey, caKey)
-	if err != nil {
-		return nil, err
-	}
-
-	saKey, saKeyPEM, err := generateRSAKey()
-	if err != nil {
-		return nil, err
-	}
-	saPubPEM, err := encodePublicKeyPEM(&saKey.PublicKey)
-	if err != nil {
-		return nil, err
-	}
-
-	kubeletKey, kubeletKeyPEM, err := generateRSAKey()
-	if err != nil {
-		return nil, err
-	}
-	kubeletTemplate := &x509.Certificate{
-		SerialNumber: big.NewInt(2),
-		Subject: pkix.Name{
-			CommonName:   "system:kube-apiserver",
-			Organization: []string{"system:masters"},
-		},
-		NotBefore: now.Add(-time.Hour),
-		NotAfter:  now.AddDate(1, 0, 0),
-		KeyUsage:  x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageClientAuth,
-		},
-	}
-	kubeletCertPEM, _, err := signCert(kubeletTemplate, caCert, &kubeletKey.PublicKey, caKey)
-	if err != nil {
-		return nil, err
-	}
-
-	apiserverKey, apiserverKeyPEM, err := generateRSAKey()
-	if err != nil {
-		return nil, err
-	}
-	apiserverTemplate := &x509.Certificate{
-		SerialNumber: big.NewInt(3),
-		Subject:      pkix.Name{CommonName: "kplane-apiserver"},
-		NotBefore:    now.Add(-time.Hour),
-		NotAfter:     now.AddDate(1, 0, 0),
-		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		DNSNames: []string{
-			"kplane-apiserver",
-			"kplane-apiserver.kplane-system",
-			"kplane-apiserver.kplane-system.svc",
-			"kplane-apiserver.kplane-system.svc.cluster.local",
-			"localhost",
-			"*.kplane.example",
-			"*.join.kplane.example",
-		},
-		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
-	}
-	apiserverCertPEM, _, err := signCert(apiserverTemplate, caCert, &apiserverKey.PublicKey, caKey)
-	if err != nil {
-		return nil, err
-	}
-
-	adminKey, adminKeyPEM, err := generateRSAKey()
-	if err != nil {
-		return nil, err
-	}
-	adminTemplate := &x509.Certificate{
-		SerialNumber: big.NewInt(4),
-		Subject:      pkix.Name{CommonName: "kplane-admin"},
-		NotBefore:    now.Add(-time.Hour),
-		NotAfter:     now.AddDate(1, 0, 0),
-		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-	}
-	adminCertPEM, _, err := signCert(adminTemplate, caCert, &adminKey.PublicKey, caKey)
-	if err != nil {
-		return nil, err
+	if regenerated {
+		logf(opts, "restarting apiserver and operator to pick up new PKI")
+		for _, name := range []string{"kplane-apiserver", "kplane-controlplane-controller-manager"} {
+			if err := kubectl.RolloutRestart(ctx, opts.Context, opts.Namespace, "deployment", name); err != nil {
+				return err
+			}
+		}
 	}
 
-	adminToken, err := generateToken()
-	if err != nil {
-		return nil, err
-	}
-
-	return &certBundle{
-		ServiceAccountKey:    saKeyPEM,
-		ServiceAccountPub:    saPubPEM,
-		ClusterCAKey:         caKeyPEM,
-		ClusterCACert:        caCertPEM,
-		KubeletClientKey:     kubeletKeyPEM,
-		KubeletClientCert:    kubeletCertPEM,
-		ApiserverTLSKey:      apiserverKeyPEM,
-		ApiserverTLSCert:     apiserverCertPEM,
-		ApiserverAdminKey:    adminKeyPEM,
-		ApiserverAdminCert:   adminCertPEM,
-		ApiserverAdminToken:  adminToken,
-		ApiserverServerName:  "kplane-apiserver",
-		ApiserverServiceAddr: "https://kplane-apiserver.kplane-system.svc.cluster.local:6443",
-	}, nil
+	return nil
 }
 
 func applySecrets(ctx context.Context, opts InstallOptions, certs *certBundle) error {
@@ -291,6 +200,30 @@ stringData:
 %s
   tls.key: |-
 %s
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: kplane-admin-client
+  namespace: %s
+type: kubernetes.io/tls
+stringData:
+  tls.crt: |-
+%s
+  tls.key: |-
+%s
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: kplane-ingress-tls
+  namespace: %s
+type: kubernetes.io/tls
+stringData:
+  tls.crt: |-
+%s
+  tls.key: |-
+%s
 `, opts.Namespace,
 		indentPEM(certs.ServiceAccountKey),
 		indentPEM(certs.ServiceAccountPub),
@@ -303,68 +236,33 @@ stringData:
 		opts.Namespace,
 		indentPEM(certs.ApiserverTLSCert),
 		indentPEM(certs.ApiserverTLSKey),
+		opts.Namespace,
+		indentPEM(certs.ApiserverAdminCert),
+		indentPEM(certs.ApiserverAdminKey),
+		opts.Namespace,
+		indentPEM(certs.IngressTLSCert),
+		indentPEM(certs.IngressTLSKey),
 	)
 	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(secretYaml)})
 }
 
-func applyEtcd(ctx context.Context, opts InstallOptions) error {
-	manifest := fmt.Sprintf(`apiVersion: v1
-kind: Service
-metadata:
-  name: kplane-etcd
-  namespace: %s
-spec:
-  selector:
-    app: kplane-etcd
-  ports:
-    - name: client
-      port: 2379
-      targetPort: 2379
-    - name: peer
-      port: 2380
-      targetPort: 2380
----
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: kplane-etcd
-  namespace: %s
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: kplane-etcd
-  template:
-    metadata:
-      labels:
-        app: kplane-etcd
-    spec:
-      containers:
-        - name: etcd
-          image: %s
-          env:
-            - name: ALLOW_NONE_AUTHENTICATION
-              value: "yes"
-            - name: ETCD_LISTEN_CLIENT_URLS
-              value: "http://0.0.0.0:2379"
-            - name: ETCD_ADVERTISE_CLIENT_URLS
-              value: "http://kplane-etcd.%s.svc.cluster.local:2379"
-            - name: ETCD_LISTEN_PEER_URLS
-              value: "http://0.0.0.0:2380"
-            - name: ETCD_INITIAL_ADVERTISE_PEER_URLS
-              value: "http://kplane-etcd.%s.svc.cluster.local:2380"
-            - name: ETCD_INITIAL_CLUSTER
-              value: "default=http://kplane-etcd.%s.svc.cluster.local:2380"
-            - name: ETCD_NAME
-              value: "default"
-          ports:
-            - containerPort: 2379
-            - containerPort: 2380
-`, opts.Namespace, opts.Namespace, opts.Images.Etcd, opts.Namespace, opts.Namespace, opts.Namespace)
-	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(manifest)})
-}
-
 func applyApiserver(ctx context.Context, opts InstallOptions) error {
+	mode, err := NormalizeAuthorizationMode(opts.AuthorizationMode)
+	if err != nil {
+		return err
+	}
+	authorizationMode, anonymousAuth := "Node,RBAC", "false"
+	if mode == AuthorizationModeAlwaysAllow {
+		authorizationMode, anonymousAuth = "AlwaysAllow", "true"
+	}
+	servers, err := etcdServers(ctx, opts)
+	if err != nil {
+		return err
+	}
+	replicas := 1
+	if opts.HA {
+		replicas = HAMembers
+	}
 	manifest := fmt.Sprintf(`apiVersion: v1
 kind: Service
 metadata:
@@ -384,7 +282,7 @@ metadata:
   name: kplane-apiserver
   namespace: %s
 spec:
-  replicas: 1
+  replicas: %d
   selector:
     matchLabels:
       app: kplane-apiserver
@@ -393,17 +291,26 @@ spec:
       labels:
         app: kplane-apiserver
     spec:
+      affinity:
+        podAntiAffinity:
+          preferredDuringSchedulingIgnoredDuringExecution:
+            - weight: 100
+              podAffinityTerm:
+                topologyKey: kubernetes.io/hostname
+                labelSelector:
+                  matchLabels:
+                    app: kplane-apiserver
       containers:
         - name: apiserver
           image: %s
           imagePullPolicy: IfNotPresent
           args:
-            - --etcd-servers=http://kplane-etcd.%s.svc.cluster.local:2379
+            - --etcd-servers=%s
             - --secure-port=6443
             - --service-cluster-ip-range=10.96.0.0/12
             - --allow-privileged=true
-            - --authorization-mode=AlwaysAllow
-            - --anonymous-auth=true
+            - --authorization-mode=%s
+            - --anonymous-auth=%s
             - --enable-bootstrap-token-auth=true
             - --api-audiences=https://kplane.local
             - --service-account-issuer=https://kplane.local
@@ -435,7 +342,7 @@ spec:
             - name: token-auth
               mountPath: /var/run/kplane/token
               readOnly: true
-      volumes:
+%s      volumes:
         - name: apiserver-tls
           secret:
             secretName: kplane-apiserver-tls
@@ -451,7 +358,23 @@ spec:
         - name: token-auth
           secret:
             secretName: apiserver-token-auth
-`, opts.Namespace, opts.Namespace, opts.Images.Apiserver, opts.Namespace)
+`, opts.Namespace, opts.Namespace, replicas, opts.Images.Apiserver, servers, authorizationMode, anonymousAuth, oidcLoopbackContainerSpec(opts.OIDCLoopbackPort))
+	if replicas > 1 {
+		manifest += fmt.Sprintf(`---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: kplane-apiserver
+  namespace: %s
+spec:
+  maxUnavailable: 1
+  selector:
+    matchLabels:
+      app: kplane-apiserver
+`, opts.Namespace)
+	} else if err := kubectl.Delete(ctx, opts.Context, "poddisruptionbudget", "kplane-apiserver", opts.Namespace); err != nil {
+		return err
+	}
 	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(manifest)})
 }
 
@@ -479,7 +402,7 @@ clusters:
 - name: kplane-apiserver
   cluster:
     server: %s
-    insecure-skip-tls-verify: true
+    certificate-authority-data: %s
 users:
 - name: kplane-admin
   user:
@@ -490,7 +413,7 @@ contexts:
     cluster: kplane-apiserver
     user: kplane-admin
 current-context: kplane-apiserver
-`, certs.ApiserverServiceAddr, certs.ApiserverAdminToken)
+`, certs.ApiserverServiceAddr, encodeBase64(certs.ClusterCACert), certs.ApiserverAdminToken)
 
 	manifest := fmt.Sprintf(`apiVersion: v1
 kind: Secret
@@ -519,15 +442,16 @@ stringData:
 	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(manifest)})
 }
 
-func applyCRDs(ctx context.Context, opts InstallOptions) error {
-	if opts.CRDSource == "" {
-		return fmt.Errorf("crd source is required when install-crds is true")
-	}
-	return kubectl.ApplyKustomize(ctx, opts.Context, opts.CRDSource)
-}
-
 func applyDefaultControlPlaneClass(ctx context.Context, opts InstallOptions) error {
-	classYaml := `apiVersion: controlplane.kplane.dev/v1alpha1
+	auth, err := AuthSpec(opts.Auth)
+	if err != nil {
+		return err
+	}
+	authYaml, err := yaml.Marshal(auth)
+	if err != nil {
+		return fmt.Errorf("encode auth spec: %w", err)
+	}
+	classYaml := fmt.Sprintf(`apiVersion: controlplane.kplane.dev/v1alpha1
 kind: ControlPlaneClass
 metadata:
   name: starter
@@ -535,11 +459,10 @@ spec:
   addons:
     - starter
   auth:
-    model: basic
-    defaultRole: admin
+%s
   modesAllowed:
     - Virtual
-`
+`, indentLiteral(string(authYaml)))
 	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(classYaml)})
 }
 
@@ -579,10 +502,34 @@ func applyOperator(ctx context.Context, opts InstallOptions) error {
 }
 
 func applyIngressController(ctx context.Context, opts InstallOptions) error {
-	const url = "https://raw.githubusercontent.com/kubernetes/ingress-nginx/controller-v1.11.3/deploy/static/provider/kind/deploy.yaml"
-	if err := kubectl.ApplyURL(ctx, opts.Context, url); err != nil {
+	objects, err := kubectl.DecodeManifest(assets.IngressNginx)
+	if err != nil {
 		return err
 	}
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if obj.GetKind() == "Deployment" && obj.GetName() == "ingress-nginx-controller" {
+			if err := addControllerArg(obj, fmt.Sprintf("--default-ssl-certificate=%s/%s", opts.Namespace, ingressTLSSecret)); err != nil {
+				return err
+			}
+			if opts.IngressLoadBalancer {
+				if err := removeHostPorts(obj); err != nil {
+					return err
+				}
+			}
+		}
+		if opts.IngressLoadBalancer && obj.GetKind() == "Service" && obj.GetName() == "ingress-nginx-controller" {
+			if err := unstructured.SetNestedField(obj.Object, "LoadBalancer", "spec", "type"); err != nil {
+				return err
+			}
+		}
+		if err := c.ApplyObject(ctx, obj); err != nil {
+			return err
+		}
+	}
 	return kubectl.RolloutStatus(ctx, opts.Context, "ingress-nginx", "deployment", "ingress-nginx-controller", 3*time.Minute)
 }
 
@@ -597,6 +544,8 @@ metadata:
     nginx.ingress.kubernetes.io/proxy-ssl-verify: "off"
 spec:
   ingressClassName: nginx
+  tls:
+    - secretName: kplane-ingress-tls
   rules:
     - http:
         paths:
@@ -611,33 +560,58 @@ spec:
 	return kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(manifest)})
 }
 
-func generateRSAKey() (*rsa.PrivateKey, []byte, error) {
-	key, err := rsa.GenerateKey(rand.Reader, 2048)
+// addControllerArg appends an argument to the ingress-nginx controller
+// container so it serves the kplane certificate for requests without SNI.
+func addControllerArg(deployment *unstructured.Unstructured, arg string) error {
+	containers, _, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
 	if err != nil {
-		return nil, nil, fmt.Errorf("generate rsa key: %w", err)
+		return fmt.Errorf("read ingress-nginx containers: %w", err)
 	}
-	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
-	return key, keyPEM, nil
-}
-
-func encodePublicKeyPEM(key *rsa.PublicKey) ([]byte, error) {
-	der, err := x509.MarshalPKIXPublicKey(key)
-	if err != nil {
-		return nil, fmt.Errorf("encode public key: %w", err)
+	for i, item := range containers {
+		container, ok := item.(map[string]any)
+		if !ok || container["name"] != "controller" {
+			continue
+		}
+		args, _, _ := unstructured.NestedStringSlice(container, "args")
+		if !containsString(args, arg) {
+			args = append(args, arg)
+		}
+		if err := unstructured.SetNestedStringSlice(container, args, "args"); err != nil {
+			return err
+		}
+		containers[i] = container
 	}
-	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	return unstructured.SetNestedSlice(deployment.Object, containers, "spec", "template", "spec", "containers")
 }
 
-func signCert(template, parent *x509.Certificate, pub *rsa.PublicKey, parentKey *rsa.PrivateKey) ([]byte, *x509.Certificate, error) {
-	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+// removeHostPorts drops the controller's hostPorts and its ingress-ready node
+// selector, so it can run on any node of a shared cluster.
+func removeHostPorts(deployment *unstructured.Unstructured) error {
+	unstructured.RemoveNestedField(deployment.Object, "spec", "template", "spec", "nodeSelector", "ingress-ready")
+	containers, _, err := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
 	if err != nil {
-		return nil, nil, fmt.Errorf("sign cert: %w", err)
+		return fmt.Errorf("read ingress-nginx containers: %w", err)
 	}
-	cert, err := x509.ParseCertificate(der)
-	if err != nil {
-		return nil, nil, fmt.Errorf("parse cert: %w", err)
+	for i, item := range containers {
+		container, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		ports, _, _ := unstructured.NestedSlice(container, "ports")
+		for j, portItem := range ports {
+			if port, ok := portItem.(map[string]any); ok {
+				delete(port, "hostPort")
+				ports[j] = port
+			}
+		}
+		if len(ports) > 0 {
+			if err := unstructured.SetNestedSlice(container, ports, "ports"); err != nil {
+				return err
+			}
+		}
+		containers[i] = container
 	}
-	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, nil
+	return unstructured.SetNestedSlice(deployment.Object, containers, "spec", "template", "spec", "containers")
 }
 
 func indentPEM(b []byte) string {
@@ -700,6 +674,26 @@ func updateDefaultNamespace(path, namespace string) error {
 	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644)
 }
 
+// managerPullPolicyPatch keeps the kubelet from pulling an operator image
+// that was side-loaded into the node, so the vendored manager.yaml stays
+// untouched.
+const managerPullPolicyPatch = `patches:
+- target:
+    kind: Deployment
+    name: controller-manager
+  patch: |-
+    apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: controller-manager
+    spec:
+      template:
+        spec:
+          containers:
+          - name: manager
+            imagePullPolicy: IfNotPresent
+`
+
 func updateManagerImage(path, image string) error {
 	raw, err := os.ReadFile(path)
 	if err != nil {
@@ -716,7 +710,8 @@ func updateManagerImage(path, image string) error {
 			lines[i] = tagLine
 		}
 	}
-	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644)
+	out := strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n" + managerPullPolicyPatch
+	return os.WriteFile(path, []byte(out), 0o644)
 }
 
 func logf(opts InstallOptions, format string, args ...any) {
@@ -728,11 +723,3 @@ func logf(opts InstallOptions, format string, args ...any) {
 func encodeBase64(data []byte) string {
 	return strings.TrimRight(base64.StdEncoding.EncodeToString(data), "\n")
 }
-
-func generateToken() (string, error) {
-	buf := make([]byte, 32)
-	if _, err := rand.Read(buf); err != nil {
-		return "", fmt.Errorf("generate token: %w", err)
-	}
-	return hex.EncodeToString(buf), nil
-}
@@ -0,0 +1,47 @@
+package latest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kplane-dev/kplane/internal/assets"
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestUpdateManagerImage(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeEmbeddedDir(assets.ControlplaneOperator, "controlplane-operator", dir); err != nil {
+		t.Fatal(err)
+	}
+	managerDir := filepath.Join(dir, "controlplane-operator", "config", "manager")
+	if err := updateManagerImage(filepath.Join(managerDir, "kustomization.yaml"), "kind.local/operator:dev"); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := kubectl.BuildKustomize(managerDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := kubectl.DecodeManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objects {
+		if obj.GetKind() != "Deployment" {
+			continue
+		}
+		containers, _, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		if err != nil || len(containers) != 1 {
+			t.Fatalf("containers = %v, %v", containers, err)
+		}
+		container := containers[0].(map[string]any)
+		if container["image"] != "kind.local/operator:dev" {
+			t.Errorf("image = %v", container["image"])
+		}
+		if container["imagePullPolicy"] != "IfNotPresent" {
+			t.Errorf("imagePullPolicy = %v, want IfNotPresent", container["imagePullPolicy"])
+		}
+		return
+	}
+	t.Fatal("no manager deployment in the kustomization output")
+}
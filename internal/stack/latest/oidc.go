@@ -0,0 +1,319 @@
+package latest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	DefaultDexImage     = "ghcr.io/dexidp/dex:v2.41.1"
+	DefaultOIDCClientID = "kplane"
+
+	oidcLoopbackImage     = "docker.io/alpine/socat:1.8.0.0"
+	oidcLoopbackContainer = "oidc-loopback"
+)
+
+type StaticUser struct {
+	Email    string
+	Username string
+	Password string
+}
+
+type OIDCServeOptions struct {
+	// InstallOptions re-render the apiserver when the issuer needs the
+	// loopback forwarder. An empty apiserver image keeps the running one.
+	InstallOptions
+	IssuerURL string
+	ClientID  string
+	Image     string
+	Users     []StaticUser
+}
+
+// ServeOIDC runs dex with a static password database behind the ingress at
+// the issuer path. When the issuer is a loopback address, the apiserver is
+// re-applied with OIDCLoopbackPort so the same URL resolves to the ingress
+// from inside its pod.
+func ServeOIDC(ctx context.Context, opts OIDCServeOptions) error {
+	issuer, err := url.Parse(opts.IssuerURL)
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		return fmt.Errorf("oidc issuer URL must be an https URL, got %q", opts.IssuerURL)
+	}
+	path := strings.TrimSuffix(issuer.Path, "/")
+	if path == "" {
+		return fmt.Errorf("oidc issuer URL needs a path (e.g. /oidc) so it does not collide with /clusters")
+	}
+	if len(opts.Users) == 0 {
+		return fmt.Errorf("at least one static user is required")
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultDexImage
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = DefaultOIDCClientID
+	}
+	config, err := dexConfig(opts)
+	if err != nil {
+		return err
+	}
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: Secret
+metadata:
+  name: kplane-dex-config
+  namespace: %s
+type: Opaque
+stringData:
+  config.yaml: |-
+%s
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: kplane-dex
+  namespace: %s
+spec:
+  selector:
+    app: kplane-dex
+  ports:
+    - name: http
+      port: 5556
+      targetPort: 5556
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kplane-dex
+  namespace: %s
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: kplane-dex
+  template:
+    metadata:
+      labels:
+        app: kplane-dex
+      annotations:
+        kplane.dev/config-hash: %s
+    spec:
+      containers:
+        - name: dex
+          image: %s
+          imagePullPolicy: IfNotPresent
+          args:
+            - dex
+            - serve
+            - /etc/dex/config.yaml
+          ports:
+            - containerPort: 5556
+          readinessProbe:
+            httpGet:
+              path: %s/.well-known/openid-configuration
+              port: 5556
+          volumeMounts:
+            - name: config
+              mountPath: /etc/dex
+              readOnly: true
+      volumes:
+        - name: config
+          secret:
+            secretName: kplane-dex-config
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: kplane-dex
+  namespace: %s
+spec:
+  ingressClassName: nginx
+  tls:
+    - secretName: kplane-ingress-tls
+  rules:
+    - http:
+        paths:
+          - path: %s
+            pathType: Prefix
+            backend:
+              service:
+                name: kplane-dex
+                port:
+                  number: 5556
+`, opts.Namespace, indentLiteral(config),
+		opts.Namespace,
+		opts.Namespace, configHash(config), opts.Image, path,
+		opts.Namespace, path,
+	)
+	logf(opts.InstallOptions, "deploying dex at %s", opts.IssuerURL)
+	if err := kubectl.Apply(ctx, kubectl.ApplyOptions{Context: opts.Context, Stdin: []byte(manifest)}); err != nil {
+		return err
+	}
+	if err := kubectl.RolloutStatus(ctx, opts.Context, opts.Namespace, "deployment", "kplane-dex", 3*time.Minute); err != nil {
+		return err
+	}
+
+	opts.OIDCLoopbackPort = OIDCLoopbackPort(opts.IssuerURL)
+	if opts.OIDCLoopbackPort == "" {
+		return nil
+	}
+	logf(opts.InstallOptions, "forwarding %s:%s to the ingress inside the apiserver pod", issuer.Hostname(), opts.OIDCLoopbackPort)
+	return reapplyApiserver(ctx, opts.InstallOptions)
+}
+
+// StopOIDC removes dex and re-applies the apiserver without the loopback
+// forwarder.
+func StopOIDC(ctx context.Context, opts InstallOptions) error {
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return err
+	}
+	for _, ref := range []struct{ resource, name string }{
+		{"ingress", "kplane-dex"},
+		{"deployment", "kplane-dex"},
+		{"service", "kplane-dex"},
+		{"secret", "kplane-dex-config"},
+	} {
+		if err := c.Delete(ctx, ref.resource, ref.name, opts.Namespace); err != nil {
+			return err
+		}
+	}
+	deployment, err := c.Get(ctx, "deployment", "kplane-apiserver", opts.Namespace)
+	if err != nil {
+		return err
+	}
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	for _, item := range containers {
+		if container, ok := item.(map[string]any); ok && container["name"] == oidcLoopbackContainer {
+			opts.OIDCLoopbackPort = ""
+			return reapplyApiserver(ctx, opts)
+		}
+	}
+	return nil
+}
+
+// OIDCLoopbackPort is the port the apiserver pod must forward for issuerURL,
+// or empty when the issuer is not on a loopback address.
+func OIDCLoopbackPort(issuerURL string) string {
+	issuer, err := url.Parse(issuerURL)
+	if err != nil || !isLoopbackHost(issuer.Hostname()) {
+		return ""
+	}
+	if port := issuer.Port(); port != "" {
+		return port
+	}
+	return "443"
+}
+
+func reapplyApiserver(ctx context.Context, opts InstallOptions) error {
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return err
+	}
+	if opts.Images.Apiserver == "" {
+		for _, w := range workloads {
+			if w.name != "kplane-apiserver" {
+				continue
+			}
+			if opts.Images.Apiserver, err = workloadImage(ctx, c, opts.Namespace, w); err != nil {
+				return err
+			}
+		}
+		if opts.Images.Apiserver == "" {
+			return fmt.Errorf("deployment kplane-apiserver not found in namespace %s; run kplane up first", opts.Namespace)
+		}
+	}
+	if err := applyApiserver(ctx, opts); err != nil {
+		return err
+	}
+	return c.RolloutStatus(ctx, opts.Namespace, "deployment", "kplane-apiserver", 3*time.Minute)
+}
+
+func oidcLoopbackContainerSpec(port string) string {
+	if port == "" {
+		return ""
+	}
+	return fmt.Sprintf(`        - name: %s
+          image: %s
+          imagePullPolicy: IfNotPresent
+          args:
+            - TCP-LISTEN:%s,fork,reuseaddr,bind=127.0.0.1
+            - TCP:ingress-nginx-controller.ingress-nginx.svc.cluster.local:443
+`, oidcLoopbackContainer, oidcLoopbackImage, port)
+}
+
+func dexConfig(opts OIDCServeOptions) (string, error) {
+	type staticPassword struct {
+		Email    string `yaml:"email"`
+		Hash     string `yaml:"hash"`
+		Username string `yaml:"username"`
+		UserID   string `yaml:"userID"`
+	}
+	passwords := make([]staticPassword, 0, len(opts.Users))
+	for _, user := range opts.Users {
+		if user.Email == "" || user.Password == "" {
+			return "", fmt.Errorf("static users need an email and a password")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("hash password for %s: %w", user.Email, err)
+		}
+		username := user.Username
+		if username == "" {
+			username = strings.SplitN(user.Email, "@", 2)[0]
+		}
+		sum := sha256.Sum256([]byte(user.Email))
+		passwords = append(passwords, staticPassword{
+			Email:    user.Email,
+			Hash:     string(hash),
+			Username: username,
+			UserID:   hex.EncodeToString(sum[:16]),
+		})
+	}
+	config := map[string]any{
+		"issuer":  opts.IssuerURL,
+		"storage": map[string]any{"type": "memory"},
+		"web":     map[string]any{"http": "0.0.0.0:5556"},
+		"oauth2": map[string]any{
+			"skipApprovalScreen": true,
+			"passwordConnector":  "local",
+		},
+		"enablePasswordDB": true,
+		"staticClients": []map[string]any{{
+			"id":     opts.ClientID,
+			"name":   "kplane",
+			"public": true,
+			"redirectURIs": []string{
+				"http://localhost:8000",
+				"http://localhost:18000",
+			},
+		}},
+		"staticPasswords": passwords,
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("encode dex config: %w", err)
+	}
+	return string(data), nil
+}
+
+func configHash(config string) string {
+	sum := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(sum[:8])
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
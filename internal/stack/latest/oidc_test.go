@@ -0,0 +1,22 @@
+package latest
+
+import "testing"
+
+func TestOIDCLoopbackPort(t *testing.T) {
+	tests := []struct {
+		issuer string
+		want   string
+	}{
+		{issuer: "https://127.0.0.1:8443/oidc", want: "8443"},
+		{issuer: "https://localhost/oidc", want: "443"},
+		{issuer: "https://[::1]:9443/oidc", want: "9443"},
+		{issuer: "https://kplane.example.com/oidc"},
+		{issuer: "https://10.0.0.5:8443/oidc"},
+		{issuer: "://bad"},
+	}
+	for _, tt := range tests {
+		if got := OIDCLoopbackPort(tt.issuer); got != tt.want {
+			t.Errorf("OIDCLoopbackPort(%q) = %q, want %q", tt.issuer, got, tt.want)
+		}
+	}
+}
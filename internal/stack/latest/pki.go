@@ -0,0 +1,479 @@
+package latest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	serviceAccountKeysSecret = "apiserver-serviceaccount-keys"
+	clusterSigningSecret     = "kplane-cluster-signing-keys"
+	kubeletClientSecret      = "kplane-kubelet-client"
+	apiserverTLSSecret       = "kplane-apiserver-tls"
+	adminClientSecret        = "kplane-admin-client"
+	ingressTLSSecret         = "kplane-ingress-tls"
+	tokenAuthSecret          = "apiserver-token-auth"
+)
+
+// RenewWithin is how close to expiry a leaf certificate may get before up
+// re-issues it instead of reusing it. certs check warns in the same window.
+const RenewWithin = 30 * 24 * time.Hour
+
+type certBundle struct {
+	ServiceAccountKey    []byte
+	ServiceAccountPub    []byte
+	ClusterCAKey         []byte
+	ClusterCACert        []byte
+	KubeletClientKey     []byte
+	KubeletClientCert    []byte
+	ApiserverTLSKey      []byte
+	ApiserverTLSCert     []byte
+	ApiserverAdminKey    []byte
+	IngressTLSKey        []byte
+	IngressTLSCert       []byte
+	ApiserverAdminCert   []byte
+	ApiserverAdminToken  string
+	ApiserverServerName  string
+	ApiserverServiceAddr string
+}
+
+type certAuthority struct {
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+type existingPKI map[string]map[string][]byte
+
+// loadOrGenerateCerts reuses the PKI already stored in the namespace so that
+// re-running install does not invalidate issued kubeconfigs. Missing pieces
+// are generated; leaf certificates are re-issued when they were not signed by
+// the stored CA, miss a required SAN or expire within RenewWithin.
+func loadOrGenerateCerts(ctx context.Context, opts InstallOptions) (*certBundle, existingPKI, error) {
+	existing, err := readExistingPKI(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.RegeneratePKI {
+		certs, err := buildCertBundle(existingPKI{}, opts.Namespace, opts.TLSSANs)
+		return certs, existing, err
+	}
+	certs, err := buildCertBundle(existing, opts.Namespace, opts.TLSSANs)
+	return certs, existing, err
+}
+
+var pkiSecrets = []string{serviceAccountKeysSecret, clusterSigningSecret, kubeletClientSecret, apiserverTLSSecret, adminClientSecret, ingressTLSSecret, tokenAuthSecret}
+
+func readExistingPKI(ctx context.Context, opts InstallOptions) (existingPKI, error) {
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return nil, err
+	}
+	existing := existingPKI{}
+	for _, name := range pkiSecrets {
+		data, err := c.GetSecret(ctx, name, opts.Namespace)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		existing[name] = data
+	}
+	return existing, nil
+}
+
+func (e existingPKI) has(secret string, keys ...string) bool {
+	data, ok := e[secret]
+	if !ok {
+		return false
+	}
+	for _, key := range keys {
+		if len(data[key]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func buildCertBundle(existing existingPKI, namespace string, extraSANs []string) (*certBundle, error) {
+	var (
+		ca  *certAuthority
+		err error
+	)
+	if existing.has(clusterSigningSecret, "ca.crt", "ca.key") {
+		ca, err = parseCA(existing[clusterSigningSecret]["ca.crt"], existing[clusterSigningSecret]["ca.key"])
+		if err != nil {
+			return nil, fmt.Errorf("existing %s: %w", clusterSigningSecret, err)
+		}
+	} else {
+		ca, err = newCA()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bundle := &certBundle{
+		ClusterCACert:        ca.certPEM,
+		ClusterCAKey:         ca.keyPEM,
+		ApiserverServerName:  "kplane-apiserver",
+		ApiserverServiceAddr: fmt.Sprintf("https://kplane-apiserver.%s.svc.cluster.local:6443", namespace),
+	}
+
+	if existing.has(serviceAccountKeysSecret, "sa.key", "sa.pub") {
+		bundle.ServiceAccountKey = existing[serviceAccountKeysSecret]["sa.key"]
+		bundle.ServiceAccountPub = existing[serviceAccountKeysSecret]["sa.pub"]
+	} else {
+		saKey, saKeyPEM, err := generateRSAKey()
+		if err != nil {
+			return nil, err
+		}
+		saPubPEM, err := encodePublicKeyPEM(&saKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		bundle.ServiceAccountKey = saKeyPEM
+		bundle.ServiceAccountPub = saPubPEM
+	}
+
+	if existing.has(kubeletClientSecret, "client.crt", "client.key") && ca.reusable(existing[kubeletClientSecret]["client.crt"], nil) {
+		bundle.KubeletClientCert = existing[kubeletClientSecret]["client.crt"]
+		bundle.KubeletClientKey = existing[kubeletClientSecret]["client.key"]
+	} else {
+		bundle.KubeletClientCert, bundle.KubeletClientKey, err = ca.issue(kubeletClientTemplate())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	apiserverTemplate := apiserverTLSTemplate(namespace, extraSANs)
+	if existing.has(apiserverTLSSecret, "tls.crt", "tls.key") && ca.reusable(existing[apiserverTLSSecret]["tls.crt"], apiserverTemplate) {
+		bundle.ApiserverTLSCert = existing[apiserverTLSSecret]["tls.crt"]
+		bundle.ApiserverTLSKey = existing[apiserverTLSSecret]["tls.key"]
+	} else {
+		bundle.ApiserverTLSCert, bundle.ApiserverTLSKey, err = ca.issue(apiserverTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ingressTemplate := ingressTLSTemplate(extraSANs)
+	if existing.has(ingressTLSSecret, "tls.crt", "tls.key") && ca.reusable(existing[ingressTLSSecret]["tls.crt"], ingressTemplate) {
+		bundle.IngressTLSCert = existing[ingressTLSSecret]["tls.crt"]
+		bundle.IngressTLSKey = existing[ingressTLSSecret]["tls.key"]
+	} else {
+		bundle.IngressTLSCert, bundle.IngressTLSKey, err = ca.issue(ingressTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if existing.has(adminClientSecret, "tls.crt", "tls.key") && ca.reusable(existing[adminClientSecret]["tls.crt"], nil) {
+		bundle.ApiserverAdminCert = existing[adminClientSecret]["tls.crt"]
+		bundle.ApiserverAdminKey = existing[adminClientSecret]["tls.key"]
+	} else {
+		bundle.ApiserverAdminCert, bundle.ApiserverAdminKey, err = ca.issue(adminTemplate())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if token := parseAdminToken(existing[tokenAuthSecret]["token.csv"]); token != "" {
+		bundle.ApiserverAdminToken = token
+	} else {
+		bundle.ApiserverAdminToken, err = generateToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+func newCA() (*certAuthority, error) {
+	now := time.Now()
+	caKey, caKeyPEM, err := generateRSAKey()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "kplane-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	caCertPEM, caCert, err := signCert(caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	return &certAuthority{cert: caCert, key: caKey, certPEM: caCertPEM, keyPEM: caKeyPEM}, nil
+}
+
+func parseCA(certPEM, keyPEM []byte) (*certAuthority, error) {
+	cert, err := parseCertPEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("ca.key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca.key: %w", err)
+	}
+	return &certAuthority{cert: cert, key: key, certPEM: certPEM, keyPEM: keyPEM}, nil
+}
+
+func (ca *certAuthority) issue(template *x509.Certificate) ([]byte, []byte, error) {
+	key, keyPEM, err := generateRSAKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serial
+	certPEM, _, err := signCert(template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+func (ca *certAuthority) signed(certPEM []byte) bool {
+	cert, err := parseCertPEM(certPEM)
+	if err != nil {
+		return false
+	}
+	if !bytes.Equal(cert.RawIssuer, ca.cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignatureFrom(ca.cert) == nil
+}
+
+// reusable reports whether a stored leaf was signed by ca, stays valid for
+// longer than RenewWithin and, given a template, covers its SANs.
+func (ca *certAuthority) reusable(certPEM []byte, template *x509.Certificate) bool {
+	if !ca.signed(certPEM) {
+		return false
+	}
+	cert, err := parseCertPEM(certPEM)
+	if err != nil || time.Until(cert.NotAfter) < RenewWithin {
+		return false
+	}
+	return template == nil || coversSANs(certPEM, template)
+}
+
+func kubeletClientTemplate() *x509.Certificate {
+	now := time.Now()
+	return &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:   "system:kube-apiserver",
+			Organization: []string{"system:masters"},
+		},
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.AddDate(1, 0, 0),
+		KeyUsage:  x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth,
+		},
+	}
+}
+
+func apiserverTLSTemplate(namespace string, extraSANs []string) *x509.Certificate {
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kplane-apiserver"},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.AddDate(1, 0, 0),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames: []string{
+			"kplane-apiserver",
+			"kplane-apiserver." + namespace,
+			"kplane-apiserver." + namespace + ".svc",
+			"kplane-apiserver." + namespace + ".svc.cluster.local",
+			"localhost",
+			"*.kplane.example",
+			"*.join.kplane.example",
+		},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	addSANs(template, extraSANs)
+	return template
+}
+
+// ingressTLSTemplate covers the hosts clients use to reach the ingress
+// controller, which terminates TLS for the external VCP endpoints.
+func ingressTLSTemplate(extraSANs []string) *x509.Certificate {
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kplane-ingress"},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.AddDate(1, 0, 0),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	addSANs(template, extraSANs)
+	return template
+}
+
+// addSANs appends hosts to the template, sorting IP literals from DNS names
+// and skipping duplicates.
+func addSANs(template *x509.Certificate, hosts []string) {
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			if !containsIP(template.IPAddresses, ip) {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+			continue
+		}
+		if !containsString(template.DNSNames, host) {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+}
+
+func coversSANs(certPEM []byte, template *x509.Certificate) bool {
+	cert, err := parseCertPEM(certPEM)
+	if err != nil {
+		return false
+	}
+	for _, name := range template.DNSNames {
+		if !containsString(cert.DNSNames, name) {
+			return false
+		}
+	}
+	for _, ip := range template.IPAddresses {
+		if !containsIP(cert.IPAddresses, ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, v := range ips {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func adminTemplate() *x509.Certificate {
+	now := time.Now()
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kplane-admin"},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.AddDate(1, 0, 0),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+}
+
+func parseAdminToken(tokenCSV []byte) string {
+	for _, line := range strings.Split(string(tokenCSV), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		if len(fields) >= 2 && fields[1] == "kplane-admin" {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+func parseCertPEM(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	return cert, nil
+}
+
+func generateRSAKey() (*rsa.PrivateKey, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate rsa key: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, keyPEM, nil
+}
+
+func encodePublicKeyPEM(key *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("encode public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+func signCert(template, parent *x509.Certificate, pub *rsa.PublicKey, parentKey *rsa.PrivateKey) ([]byte, *x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sign cert: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse cert: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial: %w", err)
+	}
+	return serial, nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,169 @@
+package latest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pkiFromBundle(b *certBundle) existingPKI {
+	return existingPKI{
+		serviceAccountKeysSecret: {"sa.key": b.ServiceAccountKey, "sa.pub": b.ServiceAccountPub},
+		clusterSigningSecret:     {"ca.crt": b.ClusterCACert, "ca.key": b.ClusterCAKey},
+		kubeletClientSecret:      {"client.crt": b.KubeletClientCert, "client.key": b.KubeletClientKey},
+		apiserverTLSSecret:       {"tls.crt": b.ApiserverTLSCert, "tls.key": b.ApiserverTLSKey},
+		adminClientSecret:        {"tls.crt": b.ApiserverAdminCert, "tls.key": b.ApiserverAdminKey},
+		ingressTLSSecret:         {"tls.crt": b.IngressTLSCert, "tls.key": b.IngressTLSKey},
+		tokenAuthSecret:          {"token.csv": []byte(b.ApiserverAdminToken + ",kplane-admin,kplane-admin,system:masters\n")},
+	}
+}
+
+func TestBuildCertBundleReuse(t *testing.T) {
+	first, err := buildCertBundle(existingPKI{}, "kplane-system", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := parseCA(first.ClusterCACert, first.ClusterCAKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		extraSANs []string
+		mutate    func(t *testing.T, e existingPKI)
+		reissued  bool
+	}{
+		{name: "unchanged"},
+		{name: "new SAN", extraSANs: []string{"kplane.lan"}, reissued: true},
+		{
+			name: "expiring",
+			mutate: func(t *testing.T, e existingPKI) {
+				template := ingressTLSTemplate(nil)
+				template.NotAfter = time.Now().Add(RenewWithin / 2)
+				cert, key, err := ca.issue(template)
+				if err != nil {
+					t.Fatal(err)
+				}
+				e[ingressTLSSecret] = map[string][]byte{"tls.crt": cert, "tls.key": key}
+			},
+			reissued: true,
+		},
+		{
+			name: "expired",
+			mutate: func(t *testing.T, e existingPKI) {
+				template := ingressTLSTemplate(nil)
+				template.NotBefore = time.Now().Add(-48 * time.Hour)
+				template.NotAfter = time.Now().Add(-time.Hour)
+				cert, key, err := ca.issue(template)
+				if err != nil {
+					t.Fatal(err)
+				}
+				e[ingressTLSSecret] = map[string][]byte{"tls.crt": cert, "tls.key": key}
+			},
+			reissued: true,
+		},
+		{
+			name: "other CA",
+			mutate: func(t *testing.T, e existingPKI) {
+				cert, key, err := other.issue(ingressTLSTemplate(nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				e[ingressTLSSecret] = map[string][]byte{"tls.crt": cert, "tls.key": key}
+			},
+			reissued: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := pkiFromBundle(first)
+			if tt.mutate != nil {
+				tt.mutate(t, existing)
+			}
+			stored := existing[ingressTLSSecret]["tls.crt"]
+			got, err := buildCertBundle(existing, "kplane-system", tt.extraSANs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.ClusterCACert, first.ClusterCACert) || got.ApiserverAdminToken != first.ApiserverAdminToken {
+				t.Fatal("CA or admin token was not reused")
+			}
+			if !bytes.Equal(got.KubeletClientCert, first.KubeletClientCert) {
+				t.Error("kubelet client certificate was re-issued")
+			}
+			if reissued := !bytes.Equal(got.IngressTLSCert, stored); reissued != tt.reissued {
+				t.Errorf("ingress certificate re-issued = %v, want %v", reissued, tt.reissued)
+			}
+			if !ca.reusable(got.IngressTLSCert, ingressTLSTemplate(tt.extraSANs)) {
+				t.Error("resulting ingress certificate is not reusable")
+			}
+		})
+	}
+}
+
+func TestAddSANs(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		wantDNS []string
+		wantIPs []string
+	}{
+		{name: "none", wantDNS: []string{"localhost"}, wantIPs: []string{"127.0.0.1"}},
+		{name: "dns and ip", hosts: []string{"kplane.lan", "192.168.1.20"}, wantDNS: []string{"localhost", "kplane.lan"}, wantIPs: []string{"127.0.0.1", "192.168.1.20"}},
+		{name: "duplicates and blanks", hosts: []string{"localhost", " kplane.lan ", "", "127.0.0.1", "kplane.lan"}, wantDNS: []string{"localhost", "kplane.lan"}, wantIPs: []string{"127.0.0.1"}},
+		{name: "ipv6", hosts: []string{"::1"}, wantDNS: []string{"localhost"}, wantIPs: []string{"127.0.0.1", "::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := ingressTLSTemplate(tt.hosts)
+			if strings.Join(template.DNSNames, ",") != strings.Join(tt.wantDNS, ",") {
+				t.Errorf("DNSNames = %v, want %v", template.DNSNames, tt.wantDNS)
+			}
+			var ips []string
+			for _, ip := range template.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+			if strings.Join(ips, ",") != strings.Join(tt.wantIPs, ",") {
+				t.Errorf("IPAddresses = %v, want %v", ips, tt.wantIPs)
+			}
+		})
+	}
+}
+
+func TestCoversSANs(t *testing.T) {
+	ca, err := newCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, _, err := ca.issue(ingressTLSTemplate([]string{"kplane.lan", "10.0.0.5"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		hosts []string
+		want  bool
+	}{
+		{name: "defaults", want: true},
+		{name: "same hosts", hosts: []string{"10.0.0.5", "kplane.lan"}, want: true},
+		{name: "subset", hosts: []string{"kplane.lan"}, want: true},
+		{name: "missing dns", hosts: []string{"other.lan"}},
+		{name: "missing ip", hosts: []string{"10.0.0.6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coversSANs(certPEM, ingressTLSTemplate(tt.hosts)); got != tt.want {
+				t.Errorf("coversSANs(%v) = %v, want %v", tt.hosts, got, tt.want)
+			}
+		})
+	}
+	if coversSANs([]byte("not pem"), ingressTLSTemplate(nil)) {
+		t.Error("coversSANs accepted an unparsable certificate")
+	}
+}
@@ -0,0 +1,123 @@
+package latest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kplane-dev/kplane/internal/assets"
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	AuthorizationModeRBAC        = "rbac"
+	AuthorizationModeAlwaysAllow = "always-allow"
+)
+
+type OperatorConfig struct {
+	VirtualAdminNamespace          string `yaml:"virtualAdminNamespace"`
+	VirtualAdminServiceAccount     string `yaml:"virtualAdminServiceAccount"`
+	VirtualAdminClusterRoleBinding string `yaml:"virtualAdminClusterRoleBinding"`
+}
+
+// NormalizeAuthorizationMode maps user input to a supported mode. An empty
+// value selects RBAC.
+func NormalizeAuthorizationMode(mode string) (string, error) {
+	switch mode {
+	case "", AuthorizationModeRBAC, "RBAC", "Node,RBAC":
+		return AuthorizationModeRBAC, nil
+	case AuthorizationModeAlwaysAllow, "AlwaysAllow":
+		return AuthorizationModeAlwaysAllow, nil
+	default:
+		return "", fmt.Errorf("unsupported authorization mode %q (use %s or %s)", mode, AuthorizationModeRBAC, AuthorizationModeAlwaysAllow)
+	}
+}
+
+// InstalledAuthorizationMode reports the authorization mode the shared
+// apiserver in namespace runs with.
+func InstalledAuthorizationMode(ctx context.Context, contextName, namespace string) (string, error) {
+	c, err := kubectl.ForContext(contextName)
+	if err != nil {
+		return "", err
+	}
+	obj, err := c.Get(ctx, "deployment", "kplane-apiserver", namespace)
+	if err != nil {
+		return "", err
+	}
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	for _, item := range containers {
+		container, ok := item.(map[string]any)
+		if !ok || container["name"] != "apiserver" {
+			continue
+		}
+		args, _, _ := unstructured.NestedStringSlice(container, "args")
+		return authorizationModeFromArgs(args)
+	}
+	return "", fmt.Errorf("deployment kplane-apiserver has no apiserver container")
+}
+
+// authorizationModeFromArgs reads --authorization-mode from apiserver args.
+// The apiserver allows every request when the flag is absent.
+func authorizationModeFromArgs(args []string) (string, error) {
+	for _, arg := range args {
+		if mode, ok := strings.CutPrefix(arg, "--authorization-mode="); ok {
+			return NormalizeAuthorizationMode(mode)
+		}
+	}
+	return AuthorizationModeAlwaysAllow, nil
+}
+
+func LoadOperatorConfig() (OperatorConfig, error) {
+	raw, err := assets.ControlplaneOperator.ReadFile("controlplane-operator/config/operatorconfig.yaml")
+	if err != nil {
+		return OperatorConfig{}, fmt.Errorf("read operator config: %w", err)
+	}
+	var cfg OperatorConfig
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return OperatorConfig{}, fmt.Errorf("parse operator config: %w", err)
+	}
+	if cfg.VirtualAdminNamespace == "" || cfg.VirtualAdminServiceAccount == "" || cfg.VirtualAdminClusterRoleBinding == "" {
+		return OperatorConfig{}, fmt.Errorf("operator config is missing virtual admin settings")
+	}
+	return cfg, nil
+}
+
+// BootstrapVirtualAdmin creates the admin service account and its
+// cluster-admin binding inside a VCP, as named by the operator config.
+func BootstrapVirtualAdmin(ctx context.Context, vcp *kubectl.Client) error {
+	cfg, err := LoadOperatorConfig()
+	if err != nil {
+		return err
+	}
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: %s
+  namespace: %s
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: %s
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cluster-admin
+subjects:
+  - kind: ServiceAccount
+    name: %s
+    namespace: %s
+`, cfg.VirtualAdminNamespace,
+		cfg.VirtualAdminServiceAccount, cfg.VirtualAdminNamespace,
+		cfg.VirtualAdminClusterRoleBinding,
+		cfg.VirtualAdminServiceAccount, cfg.VirtualAdminNamespace,
+	)
+	return vcp.Apply(ctx, []byte(manifest))
+}
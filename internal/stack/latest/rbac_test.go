@@ -0,0 +1,28 @@
+package latest
+
+import "testing"
+
+func TestAuthorizationModeFromArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "rbac", args: []string{"--secure-port=6443", "--authorization-mode=Node,RBAC"}, want: AuthorizationModeRBAC},
+		{name: "always allow", args: []string{"--authorization-mode=AlwaysAllow"}, want: AuthorizationModeAlwaysAllow},
+		{name: "flag absent", args: []string{"--secure-port=6443"}, want: AuthorizationModeAlwaysAllow},
+		{name: "unsupported", args: []string{"--authorization-mode=Webhook"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authorizationModeFromArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("authorizationModeFromArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package latest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+)
+
+// ReloadComponents are the workloads Reload can swap images on.
+var ReloadComponents = []string{"apiserver", "operator"}
+
+type ReloadOptions struct {
+	InstallOptions
+	// Component is apiserver or operator; its image is taken from Images.
+	Component      string
+	RolloutTimeout time.Duration
+}
+
+// Reload points one management plane workload at a new image and waits for
+// the rollout. The apiserver deployment is patched in place and the operator
+// is re-applied from its kustomization. When the image reference is
+// unchanged, as with a rebuilt dev tag, the workload is restarted so the
+// nodes' copy of the image is picked up.
+func Reload(ctx context.Context, opts ReloadOptions) error {
+	if opts.RolloutTimeout == 0 {
+		opts.RolloutTimeout = 5 * time.Minute
+	}
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return err
+	}
+	var name string
+	switch opts.Component {
+	case "apiserver":
+		name = "kplane-apiserver"
+	case "operator":
+		name = "kplane-controlplane-controller-manager"
+	default:
+		return fmt.Errorf("unknown component %q (expected %s)", opts.Component, strings.Join(ReloadComponents, " or "))
+	}
+	var w workload
+	for _, candidate := range workloads {
+		if candidate.name == name {
+			w = candidate
+		}
+	}
+	image := w.image(opts.Images)
+	if image == "" {
+		return fmt.Errorf("no image for %s", opts.Component)
+	}
+	current, err := workloadImage(ctx, c, opts.Namespace, w)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return fmt.Errorf("%s %s not found in namespace %s; run kplane up first", w.kind, w.name, opts.Namespace)
+	}
+
+	logf(opts.InstallOptions, "%s: %s -> %s", w.name, current, image)
+	switch opts.Component {
+	case "apiserver":
+		patch := fmt.Sprintf(`{"spec":{"template":{"spec":{"containers":[{"name":%q,"image":%q,"imagePullPolicy":"IfNotPresent"}]}}}}`, w.container, image)
+		if err := c.StrategicMergePatch(ctx, w.kind, w.name, opts.Namespace, []byte(patch)); err != nil {
+			return err
+		}
+	case "operator":
+		if err := applyOperator(ctx, opts.InstallOptions); err != nil {
+			return err
+		}
+	}
+	if current == image {
+		logf(opts.InstallOptions, "image reference unchanged; restarting %s", w.name)
+		if err := c.RolloutRestart(ctx, opts.Namespace, w.kind, w.name); err != nil {
+			return err
+		}
+	}
+	if err := c.RolloutStatus(ctx, opts.Namespace, w.kind, w.name, opts.RolloutTimeout); err != nil {
+		return fmt.Errorf("%s did not become healthy: %w", w.name, err)
+	}
+	return nil
+}
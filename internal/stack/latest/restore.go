@@ -0,0 +1,277 @@
+package latest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type RestoreOptions struct {
+	InstallOptions
+	Archive *Archive
+}
+
+// Restore installs the stack on a fresh management cluster with the PKI from
+// a backup, then replaces etcd's data with the backup snapshot. Each member's
+// volume is prepared by a helper pod, and etcdutl rebuilds the data directory
+// from the snapshot before etcd is scaled back up. The controlplanes, classes,
+// endpoints and kubeconfig secrets are re-applied last so the operator finds
+// their data in place.
+func Restore(ctx context.Context, opts RestoreOptions) error {
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return err
+	}
+	logf(opts.InstallOptions, "creating namespace %s", opts.Namespace)
+	if err := c.CreateNamespace(ctx, opts.Namespace); err != nil {
+		return err
+	}
+	logf(opts.InstallOptions, "restoring PKI secrets")
+	for name, data := range opts.Archive.Secrets {
+		if err := applyInNamespace(ctx, c, data, opts.Namespace); err != nil {
+			return fmt.Errorf("restore secret %s: %w", name, err)
+		}
+	}
+
+	opts.RegeneratePKI = false
+	if err := Install(ctx, opts.InstallOptions); err != nil {
+		return err
+	}
+	if len(opts.Archive.OperatorConfig) > 0 {
+		if err := applyInNamespace(ctx, c, opts.Archive.OperatorConfig, opts.Namespace); err != nil {
+			return fmt.Errorf("restore operator config: %w", err)
+		}
+	}
+	if err := restoreEtcdSnapshot(ctx, c, opts); err != nil {
+		return err
+	}
+	return restoreObjects(ctx, c, opts)
+}
+
+func restoreObjects(ctx context.Context, c *kubectl.Client, opts RestoreOptions) error {
+	if len(opts.Archive.Objects) == 0 {
+		return nil
+	}
+	logf(opts.InstallOptions, "restoring %d controlplane objects", len(opts.Archive.Objects))
+	for _, data := range opts.Archive.Objects {
+		objects, err := kubectl.DecodeManifest(data)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if ns := obj.GetNamespace(); ns != "" {
+				if err := c.CreateNamespace(ctx, ns); err != nil {
+					return err
+				}
+			}
+			if err := c.ApplyObject(ctx, obj); err != nil {
+				return fmt.Errorf("restore %s %s: %w", obj.GetKind(), obj.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func restoreEtcdSnapshot(ctx context.Context, c *kubectl.Client, opts RestoreOptions) error {
+	storage, members, ok, err := installedEtcd(ctx, opts.InstallOptions)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("etcd statefulset not found in %s", opts.Namespace)
+	}
+
+	logf(opts.InstallOptions, "stopping apiserver and etcd")
+	if err := c.MergePatch(ctx, "deployment", "kplane-apiserver", opts.Namespace, []byte(`{"spec":{"replicas":0}}`)); err != nil {
+		return err
+	}
+	if err := c.MergePatch(ctx, "statefulset", "kplane-etcd", opts.Namespace, []byte(`{"spec":{"replicas":0}}`)); err != nil {
+		return err
+	}
+	for i := 0; i < members; i++ {
+		if err := c.WaitForDeletion(ctx, "pod", etcdMemberName(i), opts.Namespace, 2*time.Minute); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < members; i++ {
+		member := etcdMemberName(i)
+		logf(opts.InstallOptions, "restoring snapshot into %s", member)
+		if err := restoreMember(ctx, c, opts, storage, members, member); err != nil {
+			return err
+		}
+	}
+
+	logf(opts.InstallOptions, "starting etcd")
+	if err := c.MergePatch(ctx, "statefulset", "kplane-etcd", opts.Namespace, []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, members))); err != nil {
+		return err
+	}
+	if err := c.RolloutStatus(ctx, opts.Namespace, "statefulset", "kplane-etcd", 5*time.Minute); err != nil {
+		return err
+	}
+	logf(opts.InstallOptions, "starting apiserver")
+	if err := applyApiserver(ctx, opts.InstallOptions); err != nil {
+		return err
+	}
+	if err := c.RolloutStatus(ctx, opts.Namespace, "deployment", "kplane-apiserver", 5*time.Minute); err != nil {
+		return err
+	}
+	if err := c.RolloutRestart(ctx, opts.Namespace, "deployment", "kplane-controlplane-controller-manager"); err != nil {
+		return err
+	}
+	return c.RolloutStatus(ctx, opts.Namespace, "deployment", "kplane-controlplane-controller-manager", 5*time.Minute)
+}
+
+func restoreMember(ctx context.Context, c *kubectl.Client, opts RestoreOptions, storage EtcdStorage, members int, member string) error {
+	helper := "kplane-etcd-restore-" + strings.TrimPrefix(member, "kplane-etcd-")
+	volume := fmt.Sprintf(`    - name: data
+      persistentVolumeClaim:
+        claimName: data-%s
+`, member)
+	subPath := ""
+	if storage.Mode == EtcdStorageHostPath {
+		volume = fmt.Sprintf(`    - name: data
+      hostPath:
+        path: %s
+        type: DirectoryOrCreate
+`, EtcdNodePath)
+		if members > 1 {
+			subPath = "          subPath: " + member + "\n"
+		}
+	}
+	pod := fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  restartPolicy: Never
+  containers:
+    - name: helper
+      image: %s
+      command: ["sleep", "3600"]
+      volumeMounts:
+        - name: data
+          mountPath: /var/lib/etcd
+%s  volumes:
+%s`, helper, opts.Namespace, restoreHelperImage, subPath, volume)
+	if err := c.Apply(ctx, []byte(pod)); err != nil {
+		return err
+	}
+	defer func() {
+		_ = c.Delete(context.WithoutCancel(ctx), "pod", helper, opts.Namespace)
+	}()
+	if err := c.WaitForPodRunning(ctx, opts.Namespace, helper, 3*time.Minute); err != nil {
+		return err
+	}
+	helperPod, err := c.Typed().CoreV1().Pods(opts.Namespace).Get(ctx, helper, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get pod %s: %w", helper, err)
+	}
+
+	snapshot, err := os.Open(opts.Archive.SnapshotPath)
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer snapshot.Close()
+	var stderr strings.Builder
+	if err := c.Exec(ctx, kubectl.ExecOptions{
+		Namespace: opts.Namespace,
+		Pod:       helper,
+		Command:   []string{"sh", "-c", "rm -rf /var/lib/etcd/restore && cat > /var/lib/etcd/snapshot.db"},
+		Stdin:     snapshot,
+		Stderr:    &stderr,
+	}); err != nil {
+		return fmt.Errorf("copy snapshot into %s: %w %s", member, err, stderr.String())
+	}
+
+	job := fmt.Sprintf(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      restartPolicy: Never
+      nodeName: %s
+      containers:
+        - name: restore
+          image: %s
+          command:
+            - /usr/local/bin/etcdutl
+            - snapshot
+            - restore
+            - /var/lib/etcd/snapshot.db
+            - --data-dir=/var/lib/etcd/restore
+            - --name=%s
+            - --initial-cluster=%s
+            - --initial-advertise-peer-urls=%s
+          volumeMounts:
+            - name: data
+              mountPath: /var/lib/etcd
+%s      volumes:
+%s`, helper, opts.Namespace, helperPod.Spec.NodeName, opts.Images.Etcd,
+		member, etcdInitialCluster(opts.Namespace, members), etcdPeerURL(opts.Namespace, member),
+		indentLines(subPath, "    "), indentLines(volume, "    "))
+	if err := c.Apply(ctx, []byte(job)); err != nil {
+		return err
+	}
+	background := metav1.DeletePropagationBackground
+	defer func() {
+		err := c.Typed().BatchV1().Jobs(opts.Namespace).Delete(context.WithoutCancel(ctx), helper, metav1.DeleteOptions{PropagationPolicy: &background})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logf(opts.InstallOptions, "delete job %s: %v", helper, err)
+		}
+	}()
+	if err := c.WaitForJob(ctx, opts.Namespace, helper, 5*time.Minute); err != nil {
+		return fmt.Errorf("restore snapshot into %s: %w", member, err)
+	}
+
+	stderr.Reset()
+	if err := c.Exec(ctx, kubectl.ExecOptions{
+		Namespace: opts.Namespace,
+		Pod:       helper,
+		Command:   []string{"sh", "-c", "rm -rf /var/lib/etcd/data && mv /var/lib/etcd/restore /var/lib/etcd/data && rm -f /var/lib/etcd/snapshot.db"},
+		Stderr:    &stderr,
+	}); err != nil {
+		return fmt.Errorf("swap data dir of %s: %w %s", member, err, stderr.String())
+	}
+	return nil
+}
+
+// applyInNamespace applies a single-object manifest from a backup into the
+// target namespace, which may differ from the one it was taken from.
+func applyInNamespace(ctx context.Context, c *kubectl.Client, manifest []byte, namespace string) error {
+	objects, err := kubectl.DecodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		obj.SetNamespace(namespace)
+		if err := c.ApplyObject(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indentLines(text, indent string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,296 @@
+package latest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kplane-dev/kplane/internal/kubectl"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Name identifies this manifest set in the stack registry.
+const Name = "latest"
+
+type UpgradeOptions struct {
+	InstallOptions
+	// ControlPlaneClient opens a client for a VCP. Migrations that touch VCP
+	// contents are skipped when it is nil.
+	ControlPlaneClient func(ctx context.Context, name string) (*kubectl.Client, error)
+	RolloutTimeout     time.Duration
+}
+
+// Migration is a step the stack runs before or after rolling its workloads.
+type Migration struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, opts UpgradeOptions) error
+}
+
+// PreUpgrade runs before any manifest of the target stack is applied.
+var PreUpgrade = []Migration{
+	{
+		Name:        "etcd-statefulset",
+		Description: "move etcd from the volume-less deployment to a persistent statefulset",
+		Run: func(ctx context.Context, opts UpgradeOptions) error {
+			return migrateLegacyEtcd(ctx, opts.InstallOptions)
+		},
+	},
+}
+
+// PostUpgrade runs once every workload of the target stack is healthy.
+var PostUpgrade = []Migration{
+	{
+		Name:        "bootstrap-virtual-admin",
+		Description: "create the virtual admin service account in VCPs created before RBAC was enabled",
+		Run:         bootstrapExistingControlPlanes,
+	},
+}
+
+type Change struct {
+	Kind    string
+	Name    string
+	Current string
+	Target  string
+}
+
+type UpgradePlan struct {
+	Images []Change
+	CRDs   []Change
+}
+
+func (p *UpgradePlan) Empty() bool {
+	return len(p.Images) == 0 && len(p.CRDs) == 0
+}
+
+type workload struct {
+	kind      string
+	name      string
+	container string
+	image     func(Images) string
+}
+
+// workloads are rolled in this order, each gated on its rollout status.
+var workloads = []workload{
+	{kind: "statefulset", name: "kplane-etcd", container: "etcd", image: func(i Images) string { return i.Etcd }},
+	{kind: "deployment", name: "kplane-apiserver", container: "apiserver", image: func(i Images) string { return i.Apiserver }},
+	{kind: "deployment", name: "kplane-controlplane-controller-manager", container: "manager", image: func(i Images) string { return i.Operator }},
+}
+
+// PlanUpgrade diffs the installed workload images and CRDs against the target
+// stack described by opts.
+func PlanUpgrade(ctx context.Context, opts UpgradeOptions) (*UpgradePlan, error) {
+	c, err := kubectl.ForContext(opts.Context)
+	if err != nil {
+		return nil, err
+	}
+	plan := &UpgradePlan{}
+	for _, w := range workloads {
+		current, err := workloadImage(ctx, c, opts.Namespace, w)
+		if err != nil {
+			return nil, err
+		}
+		target := w.image(opts.Images)
+		if target != "" && current != target {
+			plan.Images = append(plan.Images, Change{Kind: w.kind, Name: w.name, Current: current, Target: target})
+		}
+	}
+	if opts.InstallCRDs {
+		if plan.CRDs, err = diffCRDs(ctx, c, opts.CRDSource); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// Upgrade moves an installed management plane to this stack. CRDs are
+// applied first so the new operator never sees old schemas, then etcd, the
+// apiserver and the operator are rolled one at a time and each must become
+// healthy before the next one is touched.
+func Upgrade(ctx context.Context, opts UpgradeOptions) error {
+	if _, err := NormalizeAuthorizationMode(opts.AuthorizationMode); err != nil {
+		return err
+	}
+	if _, err := AuthSpec(opts.Auth); err != nil {
+		return err
+	}
+	if opts.RolloutTimeout == 0 {
+		opts.RolloutTimeout = 5 * time.Minute
+	}
+
+	if err := runMigrations(ctx, opts, "pre-upgrade", PreUpgrade); err != nil {
+		return err
+	}
+
+	certs, _, err := loadOrGenerateCerts(ctx, opts.InstallOptions)
+	if err != nil {
+		return err
+	}
+	logf(opts.InstallOptions, "applying secrets")
+	if err := applySecrets(ctx, opts.InstallOptions, certs); err != nil {
+		return err
+	}
+	if err := applyTokenAuthSecret(ctx, opts.InstallOptions, certs); err != nil {
+		return err
+	}
+	if err := applyApiserverKubeconfig(ctx, opts.InstallOptions, certs); err != nil {
+		return err
+	}
+	if err := applyOperatorConfig(ctx, opts.InstallOptions); err != nil {
+		return err
+	}
+
+	if opts.InstallCRDs {
+		logf(opts.InstallOptions, "applying CRDs from %s", opts.CRDSource)
+		if err := applyCRDs(ctx, opts.InstallOptions); err != nil {
+			return err
+		}
+	}
+
+	steps := map[string]func(context.Context, InstallOptions) error{
+		"kplane-etcd":                            applyEtcd,
+		"kplane-apiserver":                       applyApiserver,
+		"kplane-controlplane-controller-manager": applyOperator,
+	}
+	for _, w := range workloads {
+		logf(opts.InstallOptions, "rolling %s", w.name)
+		if err := steps[w.name](ctx, opts.InstallOptions); err != nil {
+			return err
+		}
+		if err := kubectl.RolloutStatus(ctx, opts.Context, opts.Namespace, w.kind, w.name, opts.RolloutTimeout); err != nil {
+			return fmt.Errorf("%s did not become healthy; upgrade stopped before later components: %w", w.name, err)
+		}
+	}
+
+	logf(opts.InstallOptions, "updating ingress")
+	if err := applyIngressController(ctx, opts.InstallOptions); err != nil {
+		return err
+	}
+	if err := applyIngressRoute(ctx, opts.InstallOptions); err != nil {
+		return err
+	}
+
+	logf(opts.InstallOptions, "applying default ControlPlaneClass")
+	if err := applyDefaultControlPlaneClass(ctx, opts.InstallOptions); err != nil {
+		return err
+	}
+
+	return runMigrations(ctx, opts, "post-upgrade", PostUpgrade)
+}
+
+func runMigrations(ctx context.Context, opts UpgradeOptions, phase string, migrations []Migration) error {
+	for _, migration := range migrations {
+		logf(opts.InstallOptions, "%s: %s", phase, migration.Description)
+		if err := migration.Run(ctx, opts); err != nil {
+			return fmt.Errorf("%s migration %s: %w", phase, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func bootstrapExistingControlPlanes(ctx context.Context, opts UpgradeOptions) error {
+	if opts.ControlPlaneClient == nil {
+		return nil
+	}
+	mode, err := NormalizeAuthorizationMode(opts.AuthorizationMode)
+	if err != nil || mode != AuthorizationModeRBAC {
+		return err
+	}
+	controlPlanes, err := kubectl.List(ctx, opts.Context, "controlplane", "", metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range controlPlanes.Items {
+		vcp, err := opts.ControlPlaneClient(ctx, item.GetName())
+		if err != nil {
+			logf(opts.InstallOptions, "skipping %s: %v", item.GetName(), err)
+			continue
+		}
+		if err := BootstrapVirtualAdmin(ctx, vcp); err != nil {
+			return fmt.Errorf("bootstrap %s: %w", item.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func workloadImage(ctx context.Context, c *kubectl.Client, namespace string, w workload) (string, error) {
+	obj, err := c.Get(ctx, w.kind, w.name, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	for _, item := range containers {
+		container, ok := item.(map[string]any)
+		if !ok || container["name"] != w.container {
+			continue
+		}
+		image, _ := container["image"].(string)
+		return image, nil
+	}
+	return "", nil
+}
+
+func diffCRDs(ctx context.Context, c *kubectl.Client, source string) ([]Change, error) {
+	manifest, err := crdManifest(source)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := kubectl.DecodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, target := range objects {
+		if target.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+		want := crdSummary(target)
+		current, err := c.Get(ctx, "customresourcedefinition", target.GetName(), "")
+		if apierrors.IsNotFound(err) {
+			changes = append(changes, Change{Kind: "crd", Name: target.GetName(), Current: "absent", Target: want})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if have := crdSummary(current); have != want {
+			changes = append(changes, Change{Kind: "crd", Name: target.GetName(), Current: have, Target: want})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// crdSummary names the served versions of a CRD and a short hash of their
+// schemas, so a schema change within the same version shows up in the plan.
+func crdSummary(crd *unstructured.Unstructured) string {
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	names := make([]string, 0, len(versions))
+	schemas := map[string]any{}
+	for _, item := range versions {
+		version, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := version["name"].(string)
+		if served, _ := version["served"].(bool); !served {
+			continue
+		}
+		names = append(names, name)
+		schemas[name] = version["schema"]
+	}
+	sort.Strings(names)
+	data, _ := json.Marshal(schemas)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s (schema %s)", strings.Join(names, ","), hex.EncodeToString(sum[:4]))
+}
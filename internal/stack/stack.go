@@ -0,0 +1,36 @@
+package stack
+
+// Features a stack's apiserver and operator support. Commands that rely on
+// one check the stack they install or find installed.
+const (
+	FeatureRBAC          = "rbac"
+	FeatureManagedIssuer = "managed-issuer"
+	FeatureOIDC          = "oidc"
+)
+
+type Images struct {
+	Apiserver string
+	Operator  string
+	Etcd      string
+}
+
+// Stack describes one installable version of the management plane.
+type Stack struct {
+	Version     string
+	Description string
+	Images      Images
+	CRDSource   string
+	// Manifests names the manifest set that installs this stack, e.g. the
+	// internal/stack/latest package.
+	Manifests string
+	Features  []string
+}
+
+func (s Stack) HasFeature(name string) bool {
+	for _, feature := range s.Features {
+		if feature == name {
+			return true
+		}
+	}
+	return false
+}
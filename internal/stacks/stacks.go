@@ -0,0 +1,59 @@
+package stacks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kplane-dev/kplane/internal/stack"
+	stacklatest "github.com/kplane-dev/kplane/internal/stack/latest"
+)
+
+// DefaultVersion resolves to the newest registered stack.
+const DefaultVersion = "latest"
+
+// registry holds the pinned stacks, newest first. Add a stack here, rather
+// than editing one, when a new apiserver and operator pair is released, so
+// upgrade --to can move between them.
+var registry = []stack.Stack{
+	{
+		Version:     "v0.1",
+		Description: "apiserver v0.0.10 and operator v0.0.15",
+		Images: stack.Images{
+			Apiserver: "docker.io/kplanedev/apiserver:v0.0.10",
+			Operator:  "docker.io/kplanedev/controlplane-operator:v0.0.15",
+			Etcd:      "quay.io/coreos/etcd:v3.5.13",
+		},
+		CRDSource: "https://github.com/kplane-dev/controlplane-operator//config/crd?ref=v0.0.15",
+		Manifests: stacklatest.Name,
+		Features: []string{
+			stack.FeatureRBAC,
+			stack.FeatureManagedIssuer,
+			stack.FeatureOIDC,
+		},
+	},
+}
+
+// List returns the stacks this binary can install, newest first.
+func List() []stack.Stack {
+	return append([]stack.Stack{}, registry...)
+}
+
+func Get(version string) (stack.Stack, error) {
+	if version == "" || version == DefaultVersion {
+		return registry[0], nil
+	}
+	for _, s := range registry {
+		if s.Version == version {
+			return s, nil
+		}
+	}
+	return stack.Stack{}, fmt.Errorf("unsupported stack version %q (use %s or %s)", version, DefaultVersion, strings.Join(Versions(), ", "))
+}
+
+func Versions() []string {
+	versions := make([]string, 0, len(registry))
+	for _, s := range registry {
+		versions = append(versions, s.Version)
+	}
+	return versions
+}
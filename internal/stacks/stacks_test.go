@@ -0,0 +1,51 @@
+package stacks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kplane-dev/kplane/internal/stack"
+)
+
+func TestGet(t *testing.T) {
+	newest := List()[0]
+	for _, version := range []string{"", DefaultVersion, newest.Version} {
+		got, err := Get(version)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", version, err)
+		}
+		if got.Version != newest.Version {
+			t.Errorf("Get(%q) = %s, want %s", version, got.Version, newest.Version)
+		}
+	}
+	if _, err := Get("v9.9"); err == nil {
+		t.Error("Get(v9.9) succeeded for an unregistered version")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	seen := map[string]bool{}
+	for _, s := range List() {
+		if s.Version == DefaultVersion {
+			t.Errorf("%s is an alias and must not be registered", DefaultVersion)
+		}
+		if seen[s.Version] {
+			t.Errorf("stack %s registered twice", s.Version)
+		}
+		seen[s.Version] = true
+		if s.Images.Apiserver == "" || s.Images.Operator == "" || s.Images.Etcd == "" {
+			t.Errorf("stack %s does not pin every image: %+v", s.Version, s.Images)
+		}
+		tag := s.Images.Operator[strings.LastIndex(s.Images.Operator, ":")+1:]
+		if !strings.HasSuffix(s.CRDSource, "?ref="+tag) {
+			t.Errorf("stack %s installs CRDs from %s; pin them to its operator release %s", s.Version, s.CRDSource, tag)
+		}
+		for _, feature := range s.Features {
+			switch feature {
+			case stack.FeatureRBAC, stack.FeatureManagedIssuer, stack.FeatureOIDC:
+			default:
+				t.Errorf("stack %s lists unknown feature %q", s.Version, feature)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+if [ "$#" -ne 1 ]; then
+  echo "usage: $0 <operator-ref>"
+  echo "example: $0 v0.0.15"
+  exit 1
+fi
+
+REF="$1"
+REPO="https://github.com/kplane-dev/controlplane-operator"
+BASES="config/crd/bases"
+ROOT="$(cd "$(dirname "$0")/.." && pwd)"
+DEST="${ROOT}/internal/assets/crds"
+
+TMP_DIR="$(mktemp -d)"
+cleanup() {
+  rm -rf "${TMP_DIR}"
+}
+trap cleanup EXIT
+
+git clone --quiet --depth 1 --branch "${REF}" "${REPO}" "${TMP_DIR}/operator"
+COMMIT="$(git -C "${TMP_DIR}/operator" rev-parse HEAD)"
+
+shopt -s nullglob
+crds=("${TMP_DIR}/operator/${BASES}"/*.yaml)
+if [ "${#crds[@]}" -eq 0 ]; then
+  echo "no CRDs under ${BASES} at ${REF}" >&2
+  exit 1
+fi
+
+rm -f "${DEST}"/*.yaml
+cp "${crds[@]}" "${DEST}/"
+{
+  echo "apiVersion: kustomize.config.k8s.io/v1beta1"
+  echo "kind: Kustomization"
+  echo "resources:"
+  for crd in "${crds[@]}"; do
+    echo "  - $(basename "${crd}")"
+  done
+} > "${DEST}/kustomization.yaml"
+{
+  echo "repository: ${REPO}"
+  echo "ref: ${REF}"
+  echo "commit: ${COMMIT}"
+  echo "path: ${BASES}"
+} > "${DEST}/SOURCE"
+
+echo "vendored ${#crds[@]} CRDs from ${REPO}@${REF} (${COMMIT})"